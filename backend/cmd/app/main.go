@@ -2,42 +2,115 @@ package main
 
 import (
 	"flag"
-	"log"
-	"runtime"
+	"fmt"
+	"os"
+	"strings"
 
-	"github.com/GimhaniHM/backend/internal/handlers"
+	"github.com/GimhaniHM/backend/internal/config"
 	"github.com/GimhaniHM/backend/internal/services"
-	"github.com/gin-gonic/gin"
 )
 
+const usage = `Usage:
+  app [serve] [flags]       aggregate the CSV and serve the insights over HTTP
+  app report [flags]        aggregate once and write one insight to a file or stdout
+  app ingest-check [flags]  aggregate once and check the CSV for data quality problems
+
+Run "app <command> -h" for the flags of a command.
+`
+
 func main() {
-	// flags for CSV path and listen address
-	csvPath := flag.String("data", "data/GO_test_5m.csv", "Path to transactions CSV file")
-	addr := flag.String("addr", ":8090", "HTTP listen address")
-	workers := flag.Int("workers", runtime.NumCPU(), "Number of CSV parse workers")
-	flag.Parse()
-
-	// Run concurrent aggregation
-	ca := services.NewConcurrentAggregator(*csvPath, *workers)
-	insights, err := ca.Run()
-	if err != nil {
-		log.Fatalf("aggregation error: %v", err)
+	// Without a subcommand, flags are for serve as before
+	cmd, args := "serve", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		cmd, args = args[0], args[1:]
+	}
+	switch cmd {
+	case "serve":
+		serve(args)
+	case "report":
+		os.Exit(report(args))
+	case "ingest-check":
+		os.Exit(ingestCheck(args))
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
 	}
+}
+
+// settings holds the flags shared by every command that reads the CSV. The
+// flags override the config file and environment, but only when set.
+type settings struct {
+	fs            *flag.FlagSet
+	configPath    *string
+	csvPath       *string
+	workers       *int
+	exactDistinct *bool
+	topK          *int
+	topProducts   *int
+	topRegions    *int
+	addr          *string // serve only
+}
 
-	// HTTP handlers
-	h := handlers.NewInsightHandler(insights)
+// addSettingsFlags registers the config file and aggregation option flags.
+// Their defaults are the built-in configuration.
+func addSettingsFlags(fs *flag.FlagSet) *settings {
+	def := config.Default()
+	return &settings{
+		fs:            fs,
+		configPath:    fs.String("config", os.Getenv("APP_CONFIG"), "YAML or TOML config file (default $APP_CONFIG)"),
+		csvPath:       fs.String("data", def.Data.Path, "Path to transactions CSV file"),
+		workers:       fs.Int("workers", def.Data.Workers, "Number of CSV parse workers"),
+		exactDistinct: fs.Bool("exact-distinct", def.Features.ExactDistinct, "Count unique customers exactly instead of with HyperLogLog (small datasets only)"),
+		topK:          fs.Int("topk", def.Limits.TopK, "Track top products approximately with this many counters per worker, turning off per-product insights (0 counts every product exactly)"),
+		topProducts:   fs.Int("top-products", def.Limits.TopProducts, "Keep only this many ranked products for /api/products/top (0 keeps all)"),
+		topRegions:    fs.Int("top-regions", def.Limits.TopRegions, "Keep only this many ranked regions for /api/regions/top (0 keeps all)"),
+	}
+}
 
-	router := gin.Default()
-	api := router.Group("/api")
-	{
-		api.GET("/revenue/countries", h.GetCountryRevenue)
-		api.GET("/products/top", h.GetTopProducts)
-		api.GET("/sales/monthly", h.GetMonthlySales)
-		api.GET("/regions/top", h.GetTopRegions)
+// load builds the configuration from the defaults, the config file, APP_*
+// environment variables and the flags set on the command line, in that
+// order, and validates it
+func (s *settings) load() (config.Config, error) {
+	cfg, err := config.Load(*s.configPath)
+	if err != nil {
+		return cfg, err
 	}
+	s.fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "data":
+			cfg.Data.Path = *s.csvPath
+		case "workers":
+			cfg.Data.Workers = *s.workers
+		case "exact-distinct":
+			cfg.Features.ExactDistinct = *s.exactDistinct
+		case "topk":
+			cfg.Limits.TopK = *s.topK
+		case "top-products":
+			cfg.Limits.TopProducts = *s.topProducts
+		case "top-regions":
+			cfg.Limits.TopRegions = *s.topRegions
+		case "addr":
+			cfg.Server.Addr = *s.addr
+		}
+	})
+	return cfg, cfg.Validate()
+}
 
-	log.Printf("Listening on %s", *addr)
-	if err := router.Run(*addr); err != nil {
-		log.Fatalf("server error: %v", err)
+// mustLoad is load for commands, exiting with status 2 and one line per
+// problem when the configuration is invalid
+func (s *settings) mustLoad() config.Config {
+	cfg, err := s.load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: invalid configuration:\n", s.fs.Name())
+		for _, line := range strings.Split(err.Error(), "\n") {
+			fmt.Fprintf(os.Stderr, "  %s\n", line)
+		}
+		os.Exit(2)
 	}
+	return cfg
+}
+
+// aggregator builds a ConcurrentAggregator from a validated configuration
+func aggregator(cfg config.Config) *services.ConcurrentAggregator {
+	return services.NewConcurrentAggregator(cfg.Data.Path, cfg.Data.Workers).WithOptions(cfg.Options())
 }
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+
+	"github.com/GimhaniHM/backend/internal/handlers"
+	"github.com/GimhaniHM/backend/internal/models"
+)
+
+// report aggregates the CSV once and writes one insight to -out (stdout by
+// default). The file is written to a temporary name and renamed into place,
+// so readers never see a partial report. Returns the exit code.
+func report(args []string) int {
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+	set := addSettingsFlags(fs)
+	insight := fs.String("insight", "", "Insight to write: "+strings.Join(handlers.ReportNames(), ", "))
+	format := fs.String("format", "", "Output format: json, csv, xlsx or ndjson (default from the -out extension, else json)")
+	out := fs.String("out", "-", `Output file, or "-" for stdout`)
+	fs.Parse(args)
+
+	if *insight == "" {
+		fmt.Fprintln(os.Stderr, "report: -insight is required")
+		fs.Usage()
+		return 2
+	}
+	if *format == "" {
+		*format = "json"
+		if ext := strings.TrimPrefix(filepath.Ext(*out), "."); ext == "csv" || ext == "xlsx" || ext == "ndjson" {
+			*format = ext
+		}
+	}
+	// Check the arguments before the (slow) aggregation
+	if !slices.Contains(handlers.ReportNames(), *insight) {
+		fmt.Fprintf(os.Stderr, "report: unknown insight %q; want one of %s\n", *insight, strings.Join(handlers.ReportNames(), ", "))
+		return 2
+	}
+	if !slices.Contains([]string{"json", "csv", "xlsx", "ndjson"}, *format) {
+		fmt.Fprintf(os.Stderr, "report: format must be json, csv, xlsx or ndjson\n")
+		return 2
+	}
+	cfg := set.mustLoad()
+
+	insights, err := aggregator(cfg).Run()
+	if err != nil {
+		log.Printf("aggregation error: %v", err)
+		return 1
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if *out == "-" {
+		if err := handlers.WriteReport(ctx, os.Stdout, insights, *insight, *format); err != nil {
+			log.Printf("report: %v", err)
+			return 1
+		}
+		return 0
+	}
+	if err := writeFile(*out, func(w io.Writer) error {
+		return handlers.WriteReport(ctx, w, insights, *insight, *format)
+	}); err != nil {
+		log.Printf("report: %v", err)
+		return 1
+	}
+	log.Printf("Wrote %s", *out)
+	return 0
+}
+
+// writeFile writes path through a temporary file in the same directory,
+// renaming it into place only if write succeeds
+func writeFile(path string, write func(io.Writer) error) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name()) // no-op after a successful rename
+	if err := f.Chmod(0644); err != nil {
+		f.Close()
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// ingestCheck aggregates the CSV once and prints its ingest report. Returns
+// 0 when the data is within the limits, 1 when it is not and 2 when the CSV
+// cannot be read.
+func ingestCheck(args []string) int {
+	fs := flag.NewFlagSet("ingest-check", flag.ExitOnError)
+	set := addSettingsFlags(fs)
+	maxSkipped := fs.Int("max-skipped", 0, "Fail when more rows than this are malformed (-1 disables the check)")
+	maxCollisions := fs.Int("max-collisions", -1, "Fail when more product names than this are shared by several IDs (-1 disables the check)")
+	asJSON := fs.Bool("json", false, "Print the full report as JSON instead of a summary")
+	fs.Parse(args)
+	cfg := set.mustLoad()
+
+	insights, err := aggregator(cfg).Run()
+	if err != nil {
+		log.Printf("aggregation error: %v", err)
+		return 2
+	}
+	r := insights.Ingest
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(r)
+	} else {
+		printIngestSummary(os.Stdout, r)
+	}
+
+	var failed []string
+	if *maxSkipped >= 0 && r.RowsSkipped > *maxSkipped {
+		failed = append(failed, fmt.Sprintf("%d rows skipped (max %d)", r.RowsSkipped, *maxSkipped))
+	}
+	if *maxCollisions >= 0 && len(r.NameCollisions) > *maxCollisions {
+		failed = append(failed, fmt.Sprintf("%d name collisions (max %d)", len(r.NameCollisions), *maxCollisions))
+	}
+	if len(failed) > 0 {
+		fmt.Fprintf(os.Stderr, "ingest-check failed: %s\n", strings.Join(failed, "; "))
+		return 1
+	}
+	return 0
+}
+
+// printIngestSummary writes the ingest report as human-readable text
+func printIngestSummary(w io.Writer, r models.IngestReport) {
+	fmt.Fprintf(w, "rows read:        %d\n", r.RowsRead)
+	fmt.Fprintf(w, "rows skipped:     %d\n", r.RowsSkipped)
+	fmt.Fprintf(w, "products:         %d\n", r.Products)
+	fmt.Fprintf(w, "name collisions:  %d\n", len(r.NameCollisions))
+	fmt.Fprintf(w, "renamed products: %d\n", len(r.RenamedProducts))
+	for _, c := range r.NameCollisions {
+		fmt.Fprintf(w, "  %q is used by %s\n", c.ProductName, strings.Join(c.ProductIDs, ", "))
+	}
+	for _, p := range r.RenamedProducts {
+		// Names are sorted, not in the order they were seen
+		fmt.Fprintf(w, "  %s is sold as %s (latest %q)\n", p.ProductID, strings.Join(p.Names, ", "), p.CurrentName)
+	}
+	for _, msg := range r.Warnings {
+		fmt.Fprintf(w, "warning: %s\n", msg)
+	}
+}
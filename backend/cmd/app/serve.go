@@ -0,0 +1,106 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/GimhaniHM/backend/internal/config"
+	"github.com/GimhaniHM/backend/internal/handlers"
+	"github.com/gin-gonic/gin"
+)
+
+// serve aggregates the CSV once and serves the insights over HTTP,
+// re-reading the CSV on SIGHUP unless features.reload_on_sighup is off
+func serve(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	set := addSettingsFlags(fs)
+	set.addr = fs.String("addr", config.Default().Server.Addr, "HTTP listen address")
+	fs.Parse(args)
+	cfg := set.mustLoad()
+
+	// Run concurrent aggregation
+	ca := aggregator(cfg)
+	insights, err := ca.Run()
+	if err != nil {
+		log.Fatalf("aggregation error: %v", err)
+	}
+
+	// HTTP handlers
+	h := handlers.NewInsightHandler(insights)
+
+	// Re-read the CSV on SIGHUP, keeping the current data if that fails
+	if cfg.Features.ReloadOnSIGHUP {
+		hup := make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
+		go func() {
+			for range hup {
+				log.Printf("Reloading %s", cfg.Data.Path)
+				insights, err := ca.Run()
+				if err != nil {
+					log.Printf("reload failed, keeping previous data: %v", err)
+					continue
+				}
+				h.Reload(insights)
+				log.Printf("Reload complete")
+			}
+		}()
+	}
+
+	router := gin.Default()
+	if !cfg.Features.Exports {
+		router.Use(handlers.DisableExports())
+	}
+	api := router.Group("/api")
+	{
+		api.GET("/revenue/countries", h.GetCountryRevenue)
+		api.GET("/ingest/report", h.GetIngestReport)
+		api.GET("/products/top", h.GetTopProducts)
+		api.GET("/products/pairs/top", h.GetTopPairs)
+		api.GET("/products/lifecycle", h.GetProductLifecycle)
+		api.GET("/products/launches", h.GetLaunchCohorts)
+		api.GET("/products/search", h.SearchProducts)
+		api.GET("/products/:id", h.GetProduct)
+		api.GET("/products/:id/related", h.GetRelatedProducts)
+		api.GET("/sales/monthly", h.GetMonthlySales)
+		api.GET("/regions/top", h.GetTopRegions)
+		api.GET("/countries", h.GetCountries)
+		api.GET("/countries/:country", h.GetCountry)
+		api.GET("/regions/:region", h.GetRegion)
+		api.GET("/customers/segments", h.GetCustomerSegments)
+		api.GET("/customers/:id", h.GetCustomer)
+		api.GET("/cohorts", h.GetCohorts)
+		api.GET("/inventory", h.GetInventory)
+		api.GET("/inventory/:id", h.GetInventoryProduct)
+		api.GET("/pareto/products", h.GetProductPareto)
+		api.GET("/pareto/customers", h.GetCustomerPareto)
+		api.GET("/prices/products", h.GetProductPrices)
+		api.GET("/prices/categories", h.GetCategoryPrices)
+		api.GET("/prices/changes", h.GetPriceChanges)
+		api.GET("/prices/bands", h.GetPriceBands)
+		api.GET("/distributions", h.GetDistributions)
+		api.GET("/anomalies", h.GetAnomalies)
+		api.GET("/forecast", h.GetForecast)
+		api.GET("/series", h.GetSeries)
+		api.GET("/rollup", h.GetRollup)
+		api.GET("/categories", h.GetCategoryRevenue)
+		api.GET("/categories/monthly", h.GetCategoryMonthly)
+		api.GET("/categories/regions", h.GetCategoryRegions)
+	}
+
+	srv := &http.Server{
+		Addr:              cfg.Server.Addr,
+		Handler:           router,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout.Duration,
+		ReadTimeout:       cfg.Server.ReadTimeout.Duration,
+		WriteTimeout:      cfg.Server.WriteTimeout.Duration,
+		IdleTimeout:       cfg.Server.IdleTimeout.Duration,
+	}
+	log.Printf("Listening on %s", srv.Addr)
+	if err := srv.ListenAndServe(); err != nil {
+		log.Fatalf("server error: %v", err)
+	}
+}
@@ -0,0 +1,389 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/GimhaniHM/backend/internal/services"
+	"github.com/GimhaniHM/backend/internal/utils"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix starts the name of every environment variable override, e.g.
+// APP_LIMITS_TOP_PRODUCTS for limits.top_products
+const EnvPrefix = "APP_"
+
+// Config is the application configuration. Values start from Default and are
+// overridden by the config file, then APP_* environment variables, then
+// command-line flags.
+type Config struct {
+	Data     Data     `yaml:"data" toml:"data"`
+	Schema   Schema   `yaml:"schema" toml:"schema"`
+	Limits   Limits   `yaml:"limits" toml:"limits"`
+	Analysis Analysis `yaml:"analysis" toml:"analysis"`
+	Server   Server   `yaml:"server" toml:"server"`
+	Features Features `yaml:"features" toml:"features"`
+}
+
+// Data is the transactions source
+type Data struct {
+	Path    string `yaml:"path" toml:"path"`
+	Workers int    `yaml:"workers" toml:"workers"`
+}
+
+// Schema maps the CSV layout (see utils.Schema)
+type Schema struct {
+	Delimiter  string            `yaml:"delimiter" toml:"delimiter"`
+	DateFormat string            `yaml:"date_format" toml:"date_format"`
+	Columns    map[string]string `yaml:"columns" toml:"columns"` // field -> CSV header
+}
+
+// Limits bounds result sizes and memory use
+type Limits struct {
+	TopProducts       int     `yaml:"top_products" toml:"top_products"`
+	TopRegions        int     `yaml:"top_regions" toml:"top_regions"`
+	TopK              int     `yaml:"topk" toml:"topk"`
+	BasketMaxItems    int     `yaml:"basket_max_items" toml:"basket_max_items"`
+	BasketMaxPairs    int     `yaml:"basket_max_pairs" toml:"basket_max_pairs"`
+	BasketMinCount    int     `yaml:"basket_min_count" toml:"basket_min_count"`
+	DistinctPrecision int     `yaml:"distinct_precision" toml:"distinct_precision"`
+	DigestCompression float64 `yaml:"digest_compression" toml:"digest_compression"`
+}
+
+// Analysis holds the thresholds used by the insights
+type Analysis struct {
+	RFMBins            int       `yaml:"rfm_bins" toml:"rfm_bins"`
+	VelocityMonths     int       `yaml:"velocity_months" toml:"velocity_months"`
+	LowStockDays       int       `yaml:"low_stock_days" toml:"low_stock_days"`
+	OverstockDays      int       `yaml:"overstock_days" toml:"overstock_days"`
+	ParetoA            float64   `yaml:"pareto_a" toml:"pareto_a"`
+	ParetoB            float64   `yaml:"pareto_b" toml:"pareto_b"`
+	PriceBands         []float64 `yaml:"price_bands" toml:"price_bands"`
+	PriceChangePct     float64   `yaml:"price_change_pct" toml:"price_change_pct"`
+	AnomalyDayWindow   int       `yaml:"anomaly_day_window" toml:"anomaly_day_window"`
+	AnomalyMonthWindow int       `yaml:"anomaly_month_window" toml:"anomaly_month_window"`
+	AnomalyThreshold   float64   `yaml:"anomaly_threshold" toml:"anomaly_threshold"`
+	Segments           []Segment `yaml:"segments" toml:"segments"`
+}
+
+// Segment is an RFM segment rule (see services.Segment). Each score range
+// is [min, max], inclusive; an omitted range matches any score. Rules are
+// evaluated in order and the first match wins.
+type Segment struct {
+	Name string `yaml:"name" toml:"name"`
+	R    []int  `yaml:"r" toml:"r"`
+	F    []int  `yaml:"f" toml:"f"`
+	M    []int  `yaml:"m" toml:"m"`
+}
+
+// Server configures the HTTP server. A zero timeout means no timeout.
+type Server struct {
+	Addr              string   `yaml:"addr" toml:"addr"`
+	ReadHeaderTimeout Duration `yaml:"read_header_timeout" toml:"read_header_timeout"`
+	ReadTimeout       Duration `yaml:"read_timeout" toml:"read_timeout"`
+	WriteTimeout      Duration `yaml:"write_timeout" toml:"write_timeout"`
+	IdleTimeout       Duration `yaml:"idle_timeout" toml:"idle_timeout"`
+}
+
+// Features switches optional behaviour on or off
+type Features struct {
+	ExactDistinct  bool `yaml:"exact_distinct" toml:"exact_distinct"`
+	ReloadOnSIGHUP bool `yaml:"reload_on_sighup" toml:"reload_on_sighup"`
+	Exports        bool `yaml:"exports" toml:"exports"` // CSV, XLSX and NDJSON responses
+}
+
+// Duration is a time.Duration written as a string such as "30s" or "2m"
+type Duration struct {
+	time.Duration
+}
+
+// UnmarshalText parses a duration string
+func (d *Duration) UnmarshalText(b []byte) error {
+	v, err := time.ParseDuration(string(b))
+	if err != nil {
+		return fmt.Errorf("invalid duration %q (want e.g. 30s or 2m)", b)
+	}
+	d.Duration = v
+	return nil
+}
+
+// MarshalText formats the duration as a string
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// Default returns the built-in configuration, matching
+// services.DefaultOptions
+func Default() Config {
+	opts := services.DefaultOptions()
+	return Config{
+		Data: Data{Path: "data/GO_test_5m.csv", Workers: runtime.NumCPU()},
+		Schema: Schema{
+			Delimiter:  ",",
+			DateFormat: utils.DefaultDateFormat,
+		},
+		Limits: Limits{
+			TopK:              opts.TopKCapacity,
+			TopProducts:       opts.TopProductsCap,
+			TopRegions:        opts.TopRegionsCap,
+			BasketMaxItems:    opts.BasketMaxItems,
+			BasketMaxPairs:    opts.BasketMaxPairs,
+			BasketMinCount:    opts.BasketMinCount,
+			DistinctPrecision: int(opts.DistinctPrecision),
+			DigestCompression: opts.DigestCompression,
+		},
+		Analysis: Analysis{
+			RFMBins:            opts.RFMBins,
+			VelocityMonths:     opts.VelocityMonths,
+			LowStockDays:       opts.LowStockDays,
+			OverstockDays:      opts.OverstockDays,
+			ParetoA:            opts.ParetoA,
+			ParetoB:            opts.ParetoB,
+			PriceBands:         opts.PriceBands,
+			PriceChangePct:     opts.PriceChangePct,
+			AnomalyDayWindow:   opts.AnomalyDayWindow,
+			AnomalyMonthWindow: opts.AnomalyMonthWindow,
+			AnomalyThreshold:   opts.AnomalyThreshold,
+			Segments:           configSegments(opts.Segments),
+		},
+		Server: Server{
+			Addr:              ":8090",
+			ReadHeaderTimeout: Duration{10 * time.Second},
+			ReadTimeout:       Duration{30 * time.Second},
+			IdleTimeout:       Duration{2 * time.Minute},
+			// No write timeout: exports and NDJSON streams can run long
+		},
+		Features: Features{
+			ExactDistinct:  opts.ExactDistinct,
+			ReloadOnSIGHUP: true,
+			Exports:        true,
+		},
+	}
+}
+
+// Load returns the default configuration overridden by the YAML (.yaml,
+// .yml) or TOML (.toml) file at path, if any, and then by APP_* environment
+// variables. Unknown keys are errors. The result is not validated.
+func Load(path string) (Config, error) {
+	cfg := Default()
+	if path != "" {
+		if err := cfg.readFile(path); err != nil {
+			return cfg, err
+		}
+	}
+	if err := cfg.applyEnv(os.Environ()); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
+
+// readFile decodes the config file at path over c
+func (c *Config) readFile(path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(b))
+		dec.KnownFields(true)
+		if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("config %s: %w", path, err)
+		}
+	case ".toml":
+		dec := toml.NewDecoder(bytes.NewReader(b))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(c); err != nil {
+			var derr *toml.DecodeError
+			if errors.As(err, &derr) {
+				row, col := derr.Position()
+				return fmt.Errorf("config %s:%d:%d: %s", path, row, col, derr.Error())
+			}
+			var serr *toml.StrictMissingError
+			if errors.As(err, &serr) {
+				keys := make([]string, len(serr.Errors))
+				for i, e := range serr.Errors {
+					keys[i] = strings.Join(e.Key(), ".")
+				}
+				return fmt.Errorf("config %s: unknown keys %s", path, strings.Join(keys, ", "))
+			}
+			return fmt.Errorf("config %s: %w", path, err)
+		}
+	default:
+		return fmt.Errorf("config %s: unsupported extension %q (want .yaml, .yml or .toml)", path, ext)
+	}
+	return nil
+}
+
+// Validate checks every setting, reporting all problems at once with the
+// config key each one refers to
+func (c Config) Validate() error {
+	var errs []error
+	check := func(ok bool, key, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: %s", key, fmt.Sprintf(format, args...)))
+		}
+	}
+
+	check(c.Data.Path != "", "data.path", "is required")
+	if c.Data.Path != "" {
+		if fi, err := os.Stat(c.Data.Path); err != nil {
+			check(false, "data.path", "%v", err)
+		} else {
+			check(!fi.IsDir(), "data.path", "%s is a directory, not a CSV file", c.Data.Path)
+		}
+	}
+	check(c.Data.Workers >= 1, "data.workers", "must be at least 1 (got %d)", c.Data.Workers)
+
+	check(utf8.RuneCountInString(c.Schema.Delimiter) == 1 && c.Schema.Delimiter != "\"" && c.Schema.Delimiter != "\n" && c.Schema.Delimiter != "\r",
+		"schema.delimiter", "must be a single character other than a quote or newline (got %q)", c.Schema.Delimiter)
+	if err := c.utilsSchema().Validate(); err != nil {
+		check(false, "schema", "%v", err)
+	}
+
+	l := c.Limits
+	check(l.TopProducts >= 0, "limits.top_products", "must be 0 (keep all) or more (got %d)", l.TopProducts)
+	check(l.TopRegions >= 0, "limits.top_regions", "must be 0 (keep all) or more (got %d)", l.TopRegions)
+	check(l.TopK >= 0, "limits.topk", "must be 0 (exact) or more (got %d)", l.TopK)
+	check(l.BasketMaxItems >= 2, "limits.basket_max_items", "must be at least 2 (got %d)", l.BasketMaxItems)
+	check(l.BasketMaxPairs >= 1, "limits.basket_max_pairs", "must be at least 1 (got %d)", l.BasketMaxPairs)
+	check(l.BasketMinCount >= 1, "limits.basket_min_count", "must be at least 1 (got %d)", l.BasketMinCount)
+	check(l.DistinctPrecision >= 4 && l.DistinctPrecision <= 18, "limits.distinct_precision", "must be between 4 and 18 (got %d)", l.DistinctPrecision)
+	check(l.DigestCompression >= 10, "limits.digest_compression", "must be at least 10 (got %g)", l.DigestCompression)
+
+	a := c.Analysis
+	check(a.RFMBins >= 2, "analysis.rfm_bins", "must be at least 2 (got %d)", a.RFMBins)
+	check(a.VelocityMonths >= 1, "analysis.velocity_months", "must be at least 1 (got %d)", a.VelocityMonths)
+	check(a.LowStockDays >= 0, "analysis.low_stock_days", "must not be negative (got %d)", a.LowStockDays)
+	check(a.OverstockDays > a.LowStockDays, "analysis.overstock_days", "must be greater than low_stock_days (%d, got %d)", a.LowStockDays, a.OverstockDays)
+	check(a.ParetoA > 0 && a.ParetoB >= 0 && a.ParetoA+a.ParetoB <= 100, "analysis.pareto_a, analysis.pareto_b",
+		"must satisfy a > 0, b >= 0 and a + b <= 100 (got %g, %g)", a.ParetoA, a.ParetoB)
+	check(len(a.PriceBands) > 0 && sort.Float64sAreSorted(a.PriceBands) && a.PriceBands[0] >= 0 && !hasDuplicates(a.PriceBands),
+		"analysis.price_bands", "must be non-empty, non-negative and strictly ascending (got %v)", a.PriceBands)
+	check(a.PriceChangePct >= 0, "analysis.price_change_pct", "must not be negative (got %g)", a.PriceChangePct)
+	check(a.AnomalyDayWindow >= 2, "analysis.anomaly_day_window", "must be at least 2 (got %d)", a.AnomalyDayWindow)
+	check(a.AnomalyMonthWindow >= 2, "analysis.anomaly_month_window", "must be at least 2 (got %d)", a.AnomalyMonthWindow)
+	check(a.AnomalyThreshold > 0, "analysis.anomaly_threshold", "must be positive (got %g)", a.AnomalyThreshold)
+	check(len(a.Segments) > 0, "analysis.segments", "must not be empty")
+	names := make(map[string]bool)
+	for i, seg := range a.Segments {
+		key := fmt.Sprintf("analysis.segments[%d]", i)
+		check(seg.Name != "" && !names[seg.Name], key+".name", "must be set and unique (got %q)", seg.Name)
+		names[seg.Name] = true
+		for _, r := range []struct {
+			axis  string
+			score []int
+		}{{"r", seg.R}, {"f", seg.F}, {"m", seg.M}} {
+			check(len(r.score) == 0 || (len(r.score) == 2 && r.score[0] >= 1 && r.score[0] <= r.score[1] && r.score[1] <= a.RFMBins),
+				key+"."+r.axis, "must be [min, max] with 1 <= min <= max <= rfm_bins (%d) (got %v)", a.RFMBins, r.score)
+		}
+	}
+
+	s := c.Server
+	if _, _, err := net.SplitHostPort(s.Addr); err != nil {
+		check(false, "server.addr", "must be host:port or :port (got %q)", s.Addr)
+	}
+	for key, d := range map[string]Duration{
+		"server.read_header_timeout": s.ReadHeaderTimeout,
+		"server.read_timeout":        s.ReadTimeout,
+		"server.write_timeout":       s.WriteTimeout,
+		"server.idle_timeout":        s.IdleTimeout,
+	} {
+		check(d.Duration >= 0, key, "must not be negative (got %s)", d)
+	}
+
+	// Map iteration order is random; keep the report stable
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Error() < errs[j].Error() })
+	return errors.Join(errs...)
+}
+
+// hasDuplicates reports whether a sorted slice repeats a value
+func hasDuplicates(xs []float64) bool {
+	for i := 1; i < len(xs); i++ {
+		if xs[i] == xs[i-1] {
+			return true
+		}
+	}
+	return false
+}
+
+// configSegments converts segment rules to their config form
+func configSegments(segs []services.Segment) []Segment {
+	out := make([]Segment, len(segs))
+	for i, s := range segs {
+		bounds := func(lo, hi int) []int {
+			if lo == 0 && hi == 0 {
+				return nil
+			}
+			return []int{lo, hi}
+		}
+		out[i] = Segment{Name: s.Name, R: bounds(s.RMin, s.RMax), F: bounds(s.FMin, s.FMax), M: bounds(s.MMin, s.MMax)}
+	}
+	return out
+}
+
+// serviceSegments converts validated segment rules for the aggregator
+func serviceSegments(segs []Segment) []services.Segment {
+	out := make([]services.Segment, len(segs))
+	for i, s := range segs {
+		out[i].Name = s.Name
+		if len(s.R) == 2 {
+			out[i].RMin, out[i].RMax = s.R[0], s.R[1]
+		}
+		if len(s.F) == 2 {
+			out[i].FMin, out[i].FMax = s.F[0], s.F[1]
+		}
+		if len(s.M) == 2 {
+			out[i].MMin, out[i].MMax = s.M[0], s.M[1]
+		}
+	}
+	return out
+}
+
+// utilsSchema converts the schema section for the CSV reader
+func (c Config) utilsSchema() utils.Schema {
+	s := utils.Schema{Columns: c.Schema.Columns, DateFormat: c.Schema.DateFormat}
+	if r, _ := utf8.DecodeRuneInString(c.Schema.Delimiter); r != utf8.RuneError {
+		s.Delimiter = r
+	}
+	return s
+}
+
+// Options returns the aggregation options for a validated configuration
+func (c Config) Options() services.Options {
+	opts := services.DefaultOptions()
+	opts.ExactDistinct = c.Features.ExactDistinct
+	opts.DistinctPrecision = uint8(c.Limits.DistinctPrecision)
+	opts.TopKCapacity = c.Limits.TopK
+	opts.TopProductsCap = c.Limits.TopProducts
+	opts.TopRegionsCap = c.Limits.TopRegions
+	opts.BasketMaxItems = c.Limits.BasketMaxItems
+	opts.BasketMaxPairs = c.Limits.BasketMaxPairs
+	opts.BasketMinCount = c.Limits.BasketMinCount
+	opts.DigestCompression = c.Limits.DigestCompression
+	opts.RFMBins = c.Analysis.RFMBins
+	opts.Segments = serviceSegments(c.Analysis.Segments)
+	opts.VelocityMonths = c.Analysis.VelocityMonths
+	opts.LowStockDays = c.Analysis.LowStockDays
+	opts.OverstockDays = c.Analysis.OverstockDays
+	opts.ParetoA = c.Analysis.ParetoA
+	opts.ParetoB = c.Analysis.ParetoB
+	opts.PriceBands = c.Analysis.PriceBands
+	opts.PriceChangePct = c.Analysis.PriceChangePct
+	opts.AnomalyDayWindow = c.Analysis.AnomalyDayWindow
+	opts.AnomalyMonthWindow = c.Analysis.AnomalyMonthWindow
+	opts.AnomalyThreshold = c.Analysis.AnomalyThreshold
+	opts.Schema = c.utilsSchema()
+	return opts
+}
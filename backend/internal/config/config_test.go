@@ -0,0 +1,156 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GimhaniHM/backend/internal/services"
+)
+
+// writeFile writes a config file into a temporary directory
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadYAML(t *testing.T) {
+	path := writeFile(t, "app.yaml", `
+data:
+  path: sales.csv
+schema:
+  delimiter: ";"
+  columns:
+    user_id: customer
+limits:
+  top_products: 50
+analysis:
+  price_bands: [0, 20, 40]
+server:
+  write_timeout: 5m
+features:
+  exports: false
+`)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+
+	want := Default()
+	want.Data.Path = "sales.csv"
+	want.Schema.Delimiter = ";"
+	want.Schema.Columns = map[string]string{"user_id": "customer"}
+	want.Limits.TopProducts = 50
+	want.Analysis.PriceBands = []float64{0, 20, 40}
+	want.Server.WriteTimeout = Duration{5 * time.Minute}
+	want.Features.Exports = false
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("Load = %+v; want %+v", cfg, want)
+	}
+	if opts := cfg.Options(); opts.Schema.Delimiter != ';' || opts.TopProductsCap != 50 {
+		t.Errorf("Options = %+v", opts)
+	}
+}
+
+func TestLoadTOML(t *testing.T) {
+	path := writeFile(t, "app.toml", `
+[limits]
+topk = 1000
+
+[server]
+addr = "127.0.0.1:9000"
+idle_timeout = "30s"
+
+[[analysis.segments]]
+name = "vip"
+r = [4, 5]
+m = [5, 5]
+
+[[analysis.segments]]
+name = "rest"
+`)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+	if cfg.Limits.TopK != 1000 || cfg.Server.Addr != "127.0.0.1:9000" || cfg.Server.IdleTimeout.Duration != 30*time.Second {
+		t.Errorf("Load = %+v", cfg)
+	}
+	want := []services.Segment{{Name: "vip", RMin: 4, RMax: 5, MMin: 5, MMax: 5}, {Name: "rest"}}
+	if got := cfg.Options().Segments; !reflect.DeepEqual(got, want) {
+		t.Errorf("Options().Segments = %+v; want %+v", got, want)
+	}
+}
+
+func TestLoadRejectsUnknownKeys(t *testing.T) {
+	for name, content := range map[string]string{
+		"app.yaml": "limits:\n  top_product: 5\n",
+		"app.toml": "[limits]\ntop_product = 5\n",
+	} {
+		if _, err := Load(writeFile(t, name, content)); err == nil || !strings.Contains(err.Error(), "top_product") {
+			t.Errorf("%s: err = %v; want the unknown key", name, err)
+		}
+	}
+	if _, err := Load(writeFile(t, "app.json", "{}")); err == nil {
+		t.Error("Load(app.json) succeeded; want an unsupported extension error")
+	}
+}
+
+func TestLoadEnvOverridesFile(t *testing.T) {
+	path := writeFile(t, "app.yaml", "limits:\n  top_regions: 5\n")
+	t.Setenv("APP_LIMITS_TOP_REGIONS", "9")
+	t.Setenv("APP_FEATURES_RELOAD_ON_SIGHUP", "false")
+	t.Setenv("APP_ANALYSIS_PRICE_BANDS", "0,5,50")
+	t.Setenv("APP_SERVER_READ_TIMEOUT", "1m")
+	t.Setenv("APP_SCHEMA_COLUMNS_PRODUCT_ID", "sku")
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+	if cfg.Limits.TopRegions != 9 || cfg.Features.ReloadOnSIGHUP ||
+		!reflect.DeepEqual(cfg.Analysis.PriceBands, []float64{0, 5, 50}) ||
+		cfg.Server.ReadTimeout.Duration != time.Minute ||
+		cfg.Schema.Columns["product_id"] != "sku" {
+		t.Errorf("Load = %+v", cfg)
+	}
+
+	t.Setenv("APP_DATA_WORKERS", "many")
+	t.Setenv("APP_LIMITS_TOPK", "lots")
+	if _, err := Load(""); err == nil || !strings.Contains(err.Error(), "APP_DATA_WORKERS") ||
+		!strings.Contains(err.Error(), "APP_LIMITS_TOPK") {
+		t.Errorf("err = %v; want both malformed variables", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cfg := Default()
+	cfg.Data.Path = writeFile(t, "data.csv", "")
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate(defaults) = %v", err)
+	}
+
+	cfg.Data.Workers = 0
+	cfg.Limits.TopProducts = -1
+	cfg.Schema.Delimiter = "||"
+	cfg.Schema.Columns = map[string]string{"customer": "user"}
+	cfg.Analysis.PriceBands = []float64{10, 5}
+	cfg.Server.Addr = "8090"
+	cfg.Analysis.Segments = []Segment{{Name: "vip", R: []int{5, 6}}, {Name: "vip"}}
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("Validate = nil; want errors")
+	}
+	for _, key := range []string{"data.workers", "limits.top_products", "schema.delimiter", "schema:", "analysis.price_bands", "analysis.segments[0].r", "analysis.segments[1].name", "server.addr"} {
+		if !strings.Contains(err.Error(), key) {
+			t.Errorf("Validate error %q does not mention %s", err, key)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package config
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// columnsPrefix starts schema column overrides, e.g.
+// APP_SCHEMA_COLUMNS_USER_ID=customer
+const columnsPrefix = EnvPrefix + "SCHEMA_COLUMNS_"
+
+// applyEnv overrides c with the APP_<SECTION>_<KEY> variables in environ
+// (as returned by os.Environ). Unknown APP_ variables are ignored, since
+// other tools may share the prefix; malformed values are reported together,
+// each naming its variable.
+func (c *Config) applyEnv(environ []string) error {
+	env := make(map[string]string)
+	for _, kv := range environ {
+		if k, v, ok := strings.Cut(kv, "="); ok && strings.HasPrefix(k, EnvPrefix) {
+			env[k] = v
+		}
+	}
+
+	var errs []error
+	sections := reflect.ValueOf(c).Elem()
+	for i := 0; i < sections.NumField(); i++ {
+		section := sections.Field(i)
+		sname := tagName(sections.Type().Field(i))
+		for j := 0; j < section.NumField(); j++ {
+			field := section.Field(j)
+			name := EnvPrefix + strings.ToUpper(sname+"_"+tagName(section.Type().Field(j)))
+			v, ok := env[name]
+			if !ok || field.Kind() == reflect.Map {
+				continue
+			}
+			if field.Kind() == reflect.Slice && field.Type().Elem().Kind() == reflect.Struct {
+				errs = append(errs, fmt.Errorf("%s: can only be set in the config file", name))
+				continue
+			}
+			if err := setValue(field, v); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			}
+		}
+	}
+
+	for k, v := range env {
+		if field, ok := strings.CutPrefix(k, columnsPrefix); ok {
+			if c.Schema.Columns == nil {
+				c.Schema.Columns = make(map[string]string)
+			}
+			c.Schema.Columns[strings.ToLower(field)] = v
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// tagName returns the config key of a struct field
+func tagName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+	return name
+}
+
+// setValue parses s into the config field v
+func setValue(v reflect.Value, s string) error {
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(s))
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Int:
+		n, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", s)
+		}
+		v.SetInt(int64(n))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", s)
+		}
+		v.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(strings.TrimSpace(s))
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q (want true or false)", s)
+		}
+		v.SetBool(b)
+	case reflect.Slice:
+		// Comma-separated list, e.g. APP_ANALYSIS_PRICE_BANDS=10,50,100
+		parts := strings.Split(s, ",")
+		out := reflect.MakeSlice(v.Type(), len(parts), len(parts))
+		for i, p := range parts {
+			if err := setValue(out.Index(i), p); err != nil {
+				return err
+			}
+		}
+		v.Set(out)
+	default:
+		return fmt.Errorf("unsupported setting type %s", v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// cursor is the decoded form of a next_cursor token: the snapshot it was
+// issued against, where the next page starts and a hash of the query
+// parameters that determine the row order
+type cursor struct {
+	version uint64
+	offset  int
+	query   uint32
+}
+
+// encode returns the opaque token for c
+func (cur cursor) encode() string {
+	raw := fmt.Sprintf("%d.%d.%x", cur.version, cur.offset, cur.query)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeCursor parses a token produced by cursor.encode
+func decodeCursor(token string) (cursor, error) {
+	var cur cursor
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return cur, err
+	}
+	if _, err := fmt.Sscanf(string(raw), "%d.%d.%x", &cur.version, &cur.offset, &cur.query); err != nil {
+		return cur, err
+	}
+	if cur.offset < 0 {
+		return cur, fmt.Errorf("negative offset")
+	}
+	return cur, nil
+}
+
+// queryHash fingerprints the query parameters that select and order rows,
+// so a cursor cannot be replayed against a different filter or sort.
+// Parameters that only shape the page (cursor, limit, offset, fields,
+// format) are left out.
+func queryHash(c *gin.Context) uint32 {
+	q := c.Request.URL.Query()
+	for _, k := range []string{"cursor", "limit", "offset", "fields", "format"} {
+		q.Del(k)
+	}
+	h := fnv.New32a()
+	h.Write([]byte(q.Encode())) // Encode sorts by key
+	return h.Sum32()
+}
+
+// defaultLimit is the page size when a list endpoint is given no limit
+const defaultLimit = 100
+
+// pageStart returns the page size and start position for a list served from
+// the given snapshot version. A cursor query parameter takes precedence over
+// offset. On an invalid or expired cursor it writes an error and returns
+// false.
+func pageStart(c *gin.Context, version uint64, defLimit int) (limit, offset int, ok bool) {
+	limit, offset = pageParams(c, defLimit)
+	token := c.Query("cursor")
+	if token == "" {
+		return limit, offset, true
+	}
+	cur, err := decodeCursor(token)
+	if err != nil || cur.query != queryHash(c) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor"})
+		return 0, 0, false
+	}
+	if cur.version != version {
+		c.JSON(http.StatusGone, gin.H{"error": "cursor expired: the data was reloaded, restart from the first page"})
+		return 0, 0, false
+	}
+	return limit, cur.offset, true
+}
+
+// nextCursor returns the token for the page starting at end, or "" when
+// end is past the last of total rows
+func nextCursor(c *gin.Context, version uint64, end, total int) string {
+	if end >= total {
+		return ""
+	}
+	return cursor{version: version, offset: end, query: queryHash(c)}.encode()
+}
+
+// pageWindow resolves the [offset, end) window of a list of total rows and
+// the cursor for the following page. See pageStart for error handling.
+func pageWindow(c *gin.Context, version uint64, total, defLimit int) (offset, end int, next string, ok bool) {
+	limit, offset, ok := pageStart(c, version, defLimit)
+	if !ok {
+		return 0, 0, "", false
+	}
+	if offset > total {
+		offset = total
+	}
+	// Compare before adding: offset + limit overflows for huge limits
+	end = total
+	if limit < total-offset {
+		end = offset + limit
+	}
+	return offset, end, nextCursor(c, version, end, total), true
+}
@@ -0,0 +1,432 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Export formats accepted by the format query parameter
+const (
+	formatCSV    = "csv"
+	formatXLSX   = "xlsx"
+	formatNDJSON = "ndjson"
+)
+
+// Media types for the export formats
+const (
+	mimeCSV    = "text/csv"
+	mimeXLSX   = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	mimeNDJSON = "application/x-ndjson"
+)
+
+// flushRows is how many rows are buffered before flushing to the client and
+// checking whether it has gone away
+const flushRows = 1000
+
+// exportsDisabledKey is the context key set by DisableExports
+const exportsDisabledKey = "exports_disabled"
+
+// DisableExports is middleware that turns off CSV, XLSX and NDJSON
+// responses: an explicit format parameter is rejected and the Accept header
+// is ignored, so every endpoint answers in JSON
+func DisableExports() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(exportsDisabledKey, true)
+		c.Next()
+	}
+}
+
+// exportFormat resolves the response format from the format query parameter
+// (json, csv, xlsx or ndjson), falling back to the Accept header. It returns
+// "" for JSON.
+func exportFormat(c *gin.Context) (string, error) {
+	disabled := c.GetBool(exportsDisabledKey)
+	if f := strings.ToLower(c.Query("format")); f != "" {
+		switch f {
+		case "json":
+			return "", nil
+		case formatCSV, formatXLSX, formatNDJSON:
+			if disabled {
+				return "", fmt.Errorf("exports are disabled on this server; format must be json")
+			}
+			return f, nil
+		}
+		return "", fmt.Errorf("format must be json, csv, xlsx or ndjson")
+	}
+	if disabled {
+		return "", nil
+	}
+	switch c.NegotiateFormat(gin.MIMEJSON, mimeCSV, mimeXLSX, mimeNDJSON) {
+	case mimeCSV:
+		return formatCSV, nil
+	case mimeXLSX:
+		return formatXLSX, nil
+	case mimeNDJSON:
+		return formatNDJSON, nil
+	}
+	return "", nil
+}
+
+// table is a sheet of rows exported under the given columns
+type table struct {
+	name     string
+	columns  []jsonField
+	selected bool          // columns were chosen with fields
+	rows     reflect.Value // slice of structs
+}
+
+// exportName derives a file name from the request path, e.g.
+// /api/revenue/countries becomes revenue-countries
+func exportName(c *gin.Context) string {
+	path := strings.TrimPrefix(c.Request.URL.Path, "/api/")
+	name := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-' {
+			return r
+		}
+		return '-'
+	}, strings.Trim(path, "/"))
+	if name == "" {
+		return "export"
+	}
+	return name
+}
+
+// writeExport streams tables to the client in the given export format (see
+// writeTables), naming CSV and XLSX downloads after the route
+func writeExport(c *gin.Context, format string, tables []table) {
+	switch format {
+	case formatNDJSON:
+		c.Header("Content-Type", mimeNDJSON)
+	case formatXLSX:
+		c.Header("Content-Type", mimeXLSX)
+	default:
+		c.Header("Content-Type", mimeCSV+"; charset=utf-8")
+	}
+	if format != formatNDJSON {
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", exportName(c)+"."+format))
+	}
+	c.Status(http.StatusOK)
+
+	if err := writeTables(c.Request.Context(), c.Writer, format, tables); err != nil {
+		// Headers are already sent, so the client just sees a truncated file
+		c.Error(err)
+	}
+}
+
+// writeTables writes tables as NDJSON or a CSV file (the first table only)
+// or an XLSX workbook with one sheet per table. Writing stops early once ctx
+// is done, e.g. when the client disconnects.
+func writeTables(ctx context.Context, w io.Writer, format string, tables []table) error {
+	switch format {
+	case formatNDJSON:
+		return writeNDJSON(ctx, w, tables[0])
+	case formatXLSX:
+		return writeXLSX(ctx, w, tables)
+	}
+	return writeCSVTable(ctx, w, tables[0])
+}
+
+// rowsTable makes a table of a slice of structs with the selected fields, or
+// every field when fields is nil
+func rowsTable(name string, rows reflect.Value, fields []jsonField) table {
+	t := table{name: name, columns: fields, selected: fields != nil, rows: rows}
+	if fields == nil {
+		t.columns = jsonFields(rows.Type().Elem())
+	}
+	return t
+}
+
+// objectTables makes a one-row table of struct v. In XLSX each field holding
+// a list of structs also gets its own sheet and is left out of the first; in
+// other formats such fields are written as JSON.
+func objectTables(name string, v reflect.Value, fields []jsonField, format string) []table {
+	row := reflect.Append(reflect.MakeSlice(reflect.SliceOf(v.Type()), 0, 1), v)
+	main := table{name: name, selected: fields != nil, rows: row}
+	columns := fields
+	if fields == nil {
+		columns = jsonFields(v.Type())
+	}
+	tables := []table{{}}
+	for _, f := range columns {
+		fv := v.FieldByIndex(f.index)
+		if format == formatXLSX && fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() == reflect.Struct {
+			tables = append(tables, table{name: f.name, columns: jsonFields(fv.Type().Elem()), rows: fv})
+			continue
+		}
+		main.columns = append(main.columns, f)
+	}
+	tables[0] = main
+	return tables
+}
+
+// exportRows streams rows as a single table
+func exportRows[T any](c *gin.Context, format string, rows []T, fields []jsonField) {
+	writeExport(c, format, []table{rowsTable(exportName(c), reflect.ValueOf(rows), fields)})
+}
+
+// exportObject streams v as a one-row table (see objectTables)
+func exportObject[T any](c *gin.Context, format string, v T, fields []jsonField) {
+	writeExport(c, format, objectTables(exportName(c), reflect.ValueOf(v), fields, format))
+}
+
+// cellValue renders a field for export. Numbers are reported as numeric so
+// spreadsheets can total them; nil pointers are empty and nested values are
+// written as JSON.
+func cellValue(v reflect.Value) (s string, numeric bool) {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return "", false
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), false
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), false
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), true
+	case reflect.Float32, reflect.Float64:
+		f := v.Float()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return strconv.FormatFloat(f, 'f', -1, 64), false
+		}
+		return strconv.FormatFloat(f, 'f', -1, 64), true
+	}
+	b, _ := json.Marshal(v.Interface())
+	return string(b), false
+}
+
+// escapeFormula prefixes text starting with a formula trigger (=, +, -, @,
+// tab or carriage return) with a quote, so spreadsheets opening a CSV show it
+// as text instead of evaluating it. XLSX cells are typed as inline strings,
+// which are never evaluated, so they are written as is.
+func escapeFormula(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// writeNDJSON writes one JSON object per line, flushing every flushRows rows.
+// Rows are encoded exactly as in JSON responses unless fields were selected.
+func writeNDJSON(ctx context.Context, w io.Writer, t table) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	for i := 0; i < t.rows.Len(); i++ {
+		row := t.rows.Index(i)
+		var v any = row.Interface()
+		if t.selected {
+			m := make(map[string]any, len(t.columns))
+			for _, f := range t.columns {
+				m[f.name] = row.FieldByIndex(f.index).Interface()
+			}
+			v = m
+		}
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+		if (i+1)%flushRows == 0 {
+			if err := flush(ctx, bw, w); err != nil {
+				return err
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+// flush sends buffered output on to the client, or returns the context's
+// error once the client has disconnected
+func flush(ctx context.Context, bw *bufio.Writer, w io.Writer) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+	return nil
+}
+
+// writeCSVTable writes a header row of JSON field names followed by one
+// record per row, flushing every flushRows rows
+func writeCSVTable(ctx context.Context, w io.Writer, t table) error {
+	bw := bufio.NewWriter(w)
+	cw := csv.NewWriter(bw)
+	header := make([]string, len(t.columns))
+	for i, f := range t.columns {
+		header[i] = f.name
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	record := make([]string, len(t.columns))
+	for i := 0; i < t.rows.Len(); i++ {
+		row := t.rows.Index(i)
+		for j, f := range t.columns {
+			v := row.FieldByIndex(f.index)
+			record[j], _ = cellValue(v)
+			if reflect.Indirect(v).Kind() == reflect.String {
+				record[j] = escapeFormula(record[j])
+			}
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+		if (i+1)%flushRows == 0 {
+			cw.Flush()
+			if err := flush(ctx, bw, w); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// writeXLSX writes a minimal Office Open XML workbook (no styles, inline
+// strings) with one worksheet per table
+func writeXLSX(ctx context.Context, w io.Writer, tables []table) error {
+	bw := bufio.NewWriter(w)
+	zw := zip.NewWriter(bw)
+
+	var types, rels, sheets strings.Builder
+	for i, t := range tables {
+		n := i + 1
+		fmt.Fprintf(&types, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, n)
+		fmt.Fprintf(&rels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, n, n)
+		fmt.Fprintf(&sheets, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, xmlEscape(sheetName(t.name, n)), n, n)
+	}
+	parts := []struct{ name, body string }{
+		{"[Content_Types].xml", `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+			`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+			`<Default Extension="xml" ContentType="application/xml"/>` +
+			`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+			types.String() + `</Types>`},
+		{"_rels/.rels", `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+			`</Relationships>`},
+		{"xl/workbook.xml", `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets>` + sheets.String() + `</sheets></workbook>`},
+		{"xl/_rels/workbook.xml.rels", `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			rels.String() + `</Relationships>`},
+	}
+	for _, p := range parts {
+		f, err := zw.Create(p.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, xml.Header+p.body); err != nil {
+			return err
+		}
+	}
+
+	for i, t := range tables {
+		f, err := zw.Create(fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1))
+		if err != nil {
+			return err
+		}
+		if err := writeSheet(ctx, f, t); err != nil {
+			return err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// writeSheet writes one worksheet: a header row then one row per record
+func writeSheet(ctx context.Context, w io.Writer, t table) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString(xml.Header)
+	bw.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	bw.WriteString(`<row r="1">`)
+	for j, f := range t.columns {
+		writeCell(bw, j, 1, f.name, false)
+	}
+	bw.WriteString(`</row>`)
+	for i := 0; i < t.rows.Len(); i++ {
+		row := t.rows.Index(i)
+		fmt.Fprintf(bw, `<row r="%d">`, i+2)
+		for j, f := range t.columns {
+			s, numeric := cellValue(row.FieldByIndex(f.index))
+			if s != "" {
+				writeCell(bw, j, i+2, s, numeric)
+			}
+		}
+		bw.WriteString(`</row>`)
+		if (i+1)%flushRows == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+	}
+	bw.WriteString(`</sheetData></worksheet>`)
+	return bw.Flush()
+}
+
+// writeCell writes a numeric or inline string cell at column col (0-based)
+// of row r (1-based)
+func writeCell(w *bufio.Writer, col, r int, s string, numeric bool) {
+	ref := columnName(col) + strconv.Itoa(r)
+	if numeric {
+		fmt.Fprintf(w, `<c r="%s"><v>%s</v></c>`, ref, s)
+		return
+	}
+	fmt.Fprintf(w, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, xmlEscape(s))
+}
+
+// columnName converts a 0-based column index to its letters (A, B, ..., AA)
+func columnName(col int) string {
+	name := ""
+	for col++; col > 0; col = (col - 1) / 26 {
+		name = string(rune('A'+(col-1)%26)) + name
+	}
+	return name
+}
+
+// sheetName makes a valid worksheet name: at most 31 characters without
+// []:*?/\, falling back to SheetN
+func sheetName(name string, n int) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '-'
+		}
+		return r
+	}, name)
+	if len(name) > 31 {
+		name = name[:31]
+	}
+	if name == "" {
+		return "Sheet" + strconv.Itoa(n)
+	}
+	return name
+}
+
+// xmlEscape escapes s for use in XML text or attribute values
+func xmlEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
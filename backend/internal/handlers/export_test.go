@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/GimhaniHM/backend/internal/models"
+	"github.com/GimhaniHM/backend/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportCSV(t *testing.T) {
+	for _, tc := range []struct{ format, accept string }{
+		{"&format=csv", ""},
+		{"", "text/csv"},
+	} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/revenue/countries?limit=1&sort=total_revenue:desc&fields=country,total_revenue"+tc.format, nil)
+		req.Header.Set("Accept", tc.accept)
+		listRouter().ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+		assert.Equal(t, `attachment; filename="revenue-countries.csv"`, w.Header().Get("Content-Disposition"))
+		// Exports ignore limit and include every row
+		assert.Equal(t, "country,total_revenue\nFrance,300\nUSA,200\nUSA,100\n", w.Body.String())
+	}
+}
+
+func TestExportRejectsUnknownFormat(t *testing.T) {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/revenue/countries?format=pdf", nil)
+	listRouter().ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestExportXLSX(t *testing.T) {
+	h := NewInsightHandler(services.Insights{
+		Ingest: models.IngestReport{
+			RowsRead:       3,
+			NameCollisions: []models.NameCollision{{ProductName: "Widget & Co", ProductIDs: []string{"P1", "P2"}}},
+		},
+	})
+	router := gin.New()
+	router.GET("/api/ingest/report", h.GetIngestReport)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/ingest/report?format=xlsx", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, mimeXLSX, w.Header().Get("Content-Type"))
+
+	zr, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	assert.NoError(t, err)
+	files := make(map[string]string)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		assert.NoError(t, err)
+		b, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(b)
+	}
+
+	// One sheet for the report and one per list of structs
+	assert.Contains(t, files["xl/workbook.xml"], `<sheet name="ingest-report" sheetId="1" r:id="rId1"/>`)
+	assert.Contains(t, files["xl/workbook.xml"], `<sheet name="renamed_products" sheetId="3" r:id="rId3"/>`)
+	assert.Contains(t, files["xl/worksheets/sheet1.xml"], `<c r="A2"><v>3</v></c>`)
+	assert.NotContains(t, files["xl/worksheets/sheet1.xml"], "name_collisions")
+	assert.Contains(t, files["xl/worksheets/sheet2.xml"], `<t xml:space="preserve">Widget &amp; Co</t>`)
+	assert.Contains(t, files["[Content_Types].xml"], "/xl/worksheets/sheet3.xml")
+}
+
+func TestColumnName(t *testing.T) {
+	for col, want := range map[int]string{0: "A", 25: "Z", 26: "AA", 701: "ZZ", 702: "AAA"} {
+		assert.Equal(t, want, columnName(col))
+	}
+}
+
+func TestExportNDJSON(t *testing.T) {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/revenue/countries?sort=total_revenue&fields=product_id,total_revenue", nil)
+	req.Header.Set("Accept", "application/x-ndjson")
+	listRouter().ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, mimeNDJSON, w.Header().Get("Content-Type"))
+	assert.Equal(t, `{"product_id":"p1","total_revenue":100}
+{"product_id":"p3","total_revenue":200}
+{"product_id":"p2","total_revenue":300}
+`, w.Body.String())
+}
+
+func TestExportStopsOnDisconnect(t *testing.T) {
+	rows := make([]models.CountryRevenue, 3*flushRows)
+	h := NewInsightHandler(services.Insights{CountryRevenue: rows})
+	router := gin.New()
+	router.GET("/api/revenue/countries", h.GetCountryRevenue)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequestWithContext(ctx, "GET", "/api/revenue/countries?format=ndjson", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Less(t, strings.Count(w.Body.String(), "\n"), flushRows)
+}
+
+func TestDisableExports(t *testing.T) {
+	h := NewInsightHandler(services.Insights{CountryRevenue: []models.CountryRevenue{{Country: "France"}}})
+	router := gin.New()
+	router.Use(DisableExports())
+	router.GET("/api/revenue/countries", h.GetCountryRevenue)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/revenue/countries?format=csv", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Content negotiation falls back to JSON
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/revenue/countries", nil)
+	req.Header.Set("Accept", "text/csv")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "application/json")
+}
+
+func TestExportFullLimitedLists(t *testing.T) {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/products/search?q=ball&limit=1&format=csv&fields=product_id", nil)
+	productsRouter(t).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "product_id\nP1\nP2\n", w.Body.String())
+}
+
+func TestExportEscapesFormulas(t *testing.T) {
+	h := NewInsightHandler(services.Insights{CountryRevenue: []models.CountryRevenue{
+		{Country: "=HYPERLINK(\"http://x\")", ProductID: "-1", TotalRevenue: -5},
+		{Country: "@SUM(A1)", ProductID: "p2"},
+	}})
+	router := gin.New()
+	router.GET("/api/revenue/countries", h.GetCountryRevenue)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/revenue/countries?format=csv&fields=country,product_id,total_revenue", nil)
+	router.ServeHTTP(w, req)
+
+	// Negative numbers stay numeric
+	assert.Equal(t, "country,product_id,total_revenue\n\"'=HYPERLINK(\"\"http://x\"\")\",'-1,-5\n'@SUM(A1),p2,0\n", w.Body.String())
+
+	// XLSX inline strings are never evaluated, so they keep their text
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/revenue/countries?format=xlsx&fields=country", nil)
+	router.ServeHTTP(w, req)
+	zr, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	assert.NoError(t, err)
+	for _, f := range zr.File {
+		if f.Name == "xl/worksheets/sheet1.xml" {
+			rc, _ := f.Open()
+			b, _ := io.ReadAll(rc)
+			rc.Close()
+			assert.Contains(t, string(b), `<t xml:space="preserve">@SUM(A1)</t>`)
+		}
+	}
+}
@@ -1,68 +1,743 @@
 package handlers
 
 import (
+	"math"
 	"net/http"
 	"strconv"
+	"sync/atomic"
+	"time"
 
+	"github.com/GimhaniHM/backend/internal/models"
 	"github.com/GimhaniHM/backend/internal/services"
 	"github.com/gin-gonic/gin"
 )
 
-// handles HTTP requests for precomputed insights with optional pagination
+// handles HTTP requests for precomputed insights with optional pagination.
+// List endpoints also accept sort and fields (see parseList), and every
+// endpoint can export CSV or XLSX via format or the Accept header.
 type InsightHandler struct {
-	data services.Insights
+	current atomic.Pointer[snapshot]
+}
+
+// snapshot is one immutable generation of insights. Each request reads a
+// single snapshot, so a reload never mixes old and new data in a response.
+type snapshot struct {
+	version  uint64 // identifies the generation in pagination cursors
+	data     services.Insights
+	invIndex map[string]int // product ID -> position in data.Inventory
 }
 
 // creates a new handler with the given insights
 func NewInsightHandler(ins services.Insights) *InsightHandler {
-	return &InsightHandler{data: ins}
+	h := &InsightHandler{}
+	h.Reload(ins)
+	return h
+}
+
+// Reload atomically replaces the served insights. Requests already running
+// finish against the previous snapshot; cursors issued from it expire.
+func (h *InsightHandler) Reload(ins services.Insights) {
+	invIndex := make(map[string]int, len(ins.Inventory))
+	for i, row := range ins.Inventory {
+		invIndex[row.ProductID] = i
+	}
+	// Versions come from the clock so cursors from before a restart expire too
+	version := uint64(time.Now().UnixNano())
+	if prev := h.current.Load(); prev != nil && version <= prev.version {
+		version = prev.version + 1
+	}
+	h.current.Store(&snapshot{version: version, data: ins, invIndex: invIndex})
+}
+
+// snapshot returns the insights currently being served
+func (h *InsightHandler) snapshot() *snapshot {
+	return h.current.Load()
 }
 
 // GetCountryRevenue handles GET requests to return paginated country revenue data.
 // Query parameters:
 // - limit: number of records to return (default 100)
 // - offset: starting position in the dataset (default 0)
+// - cursor: next_cursor from the previous page, used instead of offset
 func (h *InsightHandler) GetCountryRevenue(c *gin.Context) {
-	all := h.data.CountryRevenue
+	snap := h.snapshot()
+	// Return paginated data and total count
+	writePage(c, snap.version, snap.data.CountryRevenue, nil)
+}
 
-	// parse pagination params
-	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+// pageParams parses the limit (default defLimit) and offset (default 0)
+// query parameters, falling back to the defaults on invalid values
+func pageParams(c *gin.Context, defLimit int) (limit, offset int) {
+	limit, err := strconv.Atoi(c.Query("limit"))
 	if err != nil || limit < 1 {
-		limit = 100
+		limit = defLimit
 	}
-	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	offset, err = strconv.Atoi(c.DefaultQuery("offset", "0"))
 	if err != nil || offset < 0 {
 		offset = 0
 	}
+	return limit, offset
+}
 
-	// Ensure offset and end index are within bounds
-	total := len(all)
-	if offset > total {
-		offset = total
+// GetTopProducts handles GET requests to return paginated products ranked by
+// units sold.
+// Query parameters:
+// - limit: number of records to return (default 20)
+// - offset: starting position in the ranking (default 0)
+// - cursor: next_cursor from the previous page, used instead of offset
+func (h *InsightHandler) GetTopProducts(c *gin.Context) {
+	snap := h.snapshot()
+	writePageLimit(c, snap.version, snap.data.TopProducts, nil, 20)
+}
+
+// seriesParams parses the rolling (moving average length) and cumulative
+// (year-to-date and running totals) query parameters of time series
+func seriesParams(c *gin.Context) (rolling int, cumulative bool) {
+	rolling, err := strconv.Atoi(c.Query("rolling"))
+	if err != nil || rolling < 2 {
+		rolling = 0
 	}
-	end := offset + limit
-	if end > total {
-		end = total
+	cumulative, _ = strconv.ParseBool(c.Query("cumulative"))
+	return rolling, cumulative
+}
+
+// GetMonthlySales returns monthly sales volumes.
+// Query parameters:
+// - rolling: add an N-month moving average of sales volume (optional)
+// - cumulative: add year-to-date and running totals when true (optional)
+func (h *InsightHandler) GetMonthlySales(c *gin.Context) {
+	snap := h.snapshot()
+	rolling, cumulative := seriesParams(c)
+	if rolling == 0 && !cumulative {
+		writeList(c, snap.data.MonthlySales)
+		return
 	}
 
-	// Return paginated data and total count
-	c.JSON(http.StatusOK, gin.H{
-		"total": total,
-		"data":  all[offset:end],
+	rows := append([]models.MonthlySales(nil), snap.data.MonthlySales...)
+	labels := make([]string, len(rows))
+	values := make([]float64, len(rows))
+	for i, row := range rows {
+		labels[i], values[i] = row.Month, float64(row.SalesVolume)
+	}
+	for i, s := range services.DeriveSeries(labels, values, rolling, cumulative) {
+		rows[i].SeriesStats = s
+	}
+	writeList(c, rows)
+}
+
+// GetTopRegions handles GET requests to return paginated regions ranked by
+// revenue.
+// Query parameters:
+// - limit: number of records to return (default 30)
+// - offset: starting position in the ranking (default 0)
+// - cursor: next_cursor from the previous page, used instead of offset
+func (h *InsightHandler) GetTopRegions(c *gin.Context) {
+	snap := h.snapshot()
+	writePageLimit(c, snap.version, snap.data.RegionRevenue, nil, 30)
+}
+
+// GetCountries returns per-country revenue, transactions, items sold and
+// unique customers.
+func (h *InsightHandler) GetCountries(c *gin.Context) {
+	snap := h.snapshot()
+	writeList(c, snap.data.Countries)
+}
+
+// GetProduct returns one product's totals, stock status, monthly trend and
+// top countries by product ID.
+// Query parameters:
+// - countries: maximum number of top countries (default 10)
+func (h *InsightHandler) GetProduct(c *gin.Context) {
+	snap := h.snapshot()
+	n, err := strconv.Atoi(c.DefaultQuery("countries", "10"))
+	if err != nil || n < 0 {
+		n = 10
+	}
+	if snap.data.Products == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "product not found"})
+		return
+	}
+	d, ok := snap.data.Products.Detail(c.Param("id"), n)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "product not found"})
+		return
+	}
+	writeObject(c, d)
+}
+
+// SearchProducts finds products by ID, name prefix or substring, ignoring
+// case. Exact ID matches rank first, then prefix and substring matches,
+// each by revenue.
+// Query parameters:
+// - q: search text (required)
+// - limit: maximum number of results (default 20)
+// - offset: starting position in the results (default 0)
+// - cursor: next_cursor from the previous page, used instead of offset
+func (h *InsightHandler) SearchProducts(c *gin.Context) {
+	snap := h.snapshot()
+	q := c.Query("q")
+	if q == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "q is required"})
+		return
+	}
+	matches := []models.ProductMatch{}
+	if snap.data.Products != nil {
+		_, matches = snap.data.Products.Search(q, math.MaxInt)
+	}
+	writePageLimit(c, snap.version, matches, nil, 20)
+}
+
+// GetCountry returns one country's KPIs with breakdowns by region, product
+// and month.
+// Query parameters:
+// - limit: maximum number of products (default 20)
+func (h *InsightHandler) GetCountry(c *gin.Context) {
+	h.serveDrilldown(c, c.Param("country"), false)
+}
+
+// GetRegion returns one region's KPIs with breakdowns by country, product
+// and month.
+// Query parameters:
+// - limit: maximum number of products (default 20)
+func (h *InsightHandler) GetRegion(c *gin.Context) {
+	h.serveDrilldown(c, c.Param("region"), true)
+}
+
+// serveDrilldown looks up a country or region drill-down
+func (h *InsightHandler) serveDrilldown(c *gin.Context, name string, region bool) {
+	snap := h.snapshot()
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 0 {
+		limit = 20
+	}
+	var (
+		dd models.Drilldown
+		ok bool
+	)
+	switch {
+	case snap.data.Drill == nil:
+	case region:
+		dd, ok = snap.data.Drill.Region(name, limit)
+	default:
+		dd, ok = snap.data.Drill.Country(name, limit)
+	}
+	if !ok {
+		level := "country"
+		if region {
+			level = "region"
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": level + " not found"})
+		return
+	}
+	writeObject(c, dd)
+}
+
+// GetIngestReport returns row counts and data quality warnings from loading
+// the CSV, including product names shared by several product IDs.
+func (h *InsightHandler) GetIngestReport(c *gin.Context) {
+	snap := h.snapshot()
+	writeObject(c, snap.data.Ingest)
+}
+
+// GetCustomerSegments returns customer counts and revenue for each RFM segment.
+func (h *InsightHandler) GetCustomerSegments(c *gin.Context) {
+	snap := h.snapshot()
+	writeList(c, snap.data.CustomerSegments)
+}
+
+// GetCustomer returns the RFM profile of a single customer by user ID.
+func (h *InsightHandler) GetCustomer(c *gin.Context) {
+	snap := h.snapshot()
+	profile, ok := snap.data.Customers[c.Param("id")]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "customer not found"})
+		return
+	}
+	writeObject(c, profile)
+}
+
+// GetCohorts returns monthly acquisition cohorts with their retention and
+// revenue matrices.
+func (h *InsightHandler) GetCohorts(c *gin.Context) {
+	snap := h.snapshot()
+	writeList(c, snap.data.Cohorts)
+}
+
+// GetTopPairs returns the most frequently co-purchased product pairs, where a
+// basket is all purchases by one user on one date.
+// Query parameters:
+// - limit: number of pairs to return (default 20)
+// - offset: starting position in the ranking (default 0)
+// - cursor: next_cursor from the previous page, used instead of offset
+func (h *InsightHandler) GetTopPairs(c *gin.Context) {
+	snap := h.snapshot()
+	extra := gin.H{"baskets": 0, "approximate": false}
+	if b := snap.data.Baskets; b != nil {
+		extra = gin.H{"baskets": b.Baskets, "approximate": b.Approximate}
+	}
+	writePageLimit(c, snap.version, snap.data.Baskets.TopPairs(math.MaxInt), extra, 20)
+}
+
+// GetRelatedProducts returns products frequently bought together with the
+// given product ID, ranked by lift.
+// Query parameters:
+// - limit: number of related products to return (default 10)
+// - offset: starting position in the ranking (default 0)
+// - cursor: next_cursor from the previous page, used instead of offset
+func (h *InsightHandler) GetRelatedProducts(c *gin.Context) {
+	snap := h.snapshot()
+	id := c.Param("id")
+	related, ok := snap.data.Baskets.Related(id, math.MaxInt)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "product not found"})
+		return
+	}
+	writePageLimit(c, snap.version, related, gin.H{
+		"product_id":   id,
+		"product_name": snap.data.Baskets.ProductName(id),
+		"baskets":      snap.data.Baskets.BasketCount(id),
+		"approximate":  snap.data.Baskets.Approximate,
+	}, 10)
+}
+
+// GetInventory handles GET requests to return paginated inventory health,
+// ordered by days of cover (most at risk first). at_risk covers both
+// out_of_stock and low_stock products.
+// Query parameters:
+// - status: at_risk (default), out_of_stock, low_stock, overstock, ok or all
+// - limit: number of records to return (default 100)
+// - offset: starting position in the dataset (default 0)
+// - cursor: next_cursor from the previous page, used instead of offset
+func (h *InsightHandler) GetInventory(c *gin.Context) {
+	snap := h.snapshot()
+	status := c.DefaultQuery("status", "at_risk")
+	match := func(s string) bool { return s == status }
+	switch status {
+	case "at_risk":
+		match = func(s string) bool { return s == services.StockOut || s == services.StockLow }
+	case "all":
+		match = func(string) bool { return true }
+	case services.StockOut, services.StockLow, services.StockOver, services.StockOK:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid status: " + status})
+		return
+	}
+
+	all := make([]models.InventoryStatus, 0)
+	for _, row := range snap.data.Inventory {
+		if match(row.Status) {
+			all = append(all, row)
+		}
+	}
+	writePage(c, snap.version, all, nil)
+}
+
+// GetInventoryProduct returns the inventory status and monthly stock series
+// of a single product ID.
+func (h *InsightHandler) GetInventoryProduct(c *gin.Context) {
+	snap := h.snapshot()
+	id := c.Param("id")
+	i, ok := snap.invIndex[id]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "product not found"})
+		return
+	}
+	writeObject(c, models.InventoryDetail{
+		InventoryStatus: snap.data.Inventory[i],
+		Series:          snap.data.StockSeries[id],
 	})
 }
 
-// GetTopProducts returns the top-20 most purchased products.
-func (h *InsightHandler) GetTopProducts(c *gin.Context) {
-	c.JSON(http.StatusOK, h.data.TopProducts)
+// GetProductLifecycle handles GET requests to return paginated product
+// lifecycle metrics, newest launches first.
+// Query parameters:
+// - limit: number of records to return (default 100)
+// - offset: starting position in the dataset (default 0)
+// - cursor: next_cursor from the previous page, used instead of offset
+func (h *InsightHandler) GetProductLifecycle(c *gin.Context) {
+	snap := h.snapshot()
+	writePage(c, snap.version, snap.data.ProductLifecycle, nil)
 }
 
-// GetMonthlySales returns monthly sales volumes.
-func (h *InsightHandler) GetMonthlySales(c *gin.Context) {
-	c.JSON(http.StatusOK, h.data.MonthlySales)
+// GetLaunchCohorts returns new-product performance grouped by the month the
+// products were added.
+func (h *InsightHandler) GetLaunchCohorts(c *gin.Context) {
+	snap := h.snapshot()
+	writeList(c, snap.data.LaunchCohorts)
 }
 
-// GetTopRegions returns the top-30 regions by revenue.
-func (h *InsightHandler) GetTopRegions(c *gin.Context) {
-	c.JSON(http.StatusOK, h.data.RegionRevenue)
+// GetProductPareto returns products ranked by revenue with ABC tiers and the
+// cumulative revenue share curve.
+func (h *InsightHandler) GetProductPareto(c *gin.Context) {
+	snap := h.snapshot()
+	h.servePareto(c, snap.version, snap.data.ProductPareto)
+}
+
+// GetCustomerPareto returns customers ranked by revenue with ABC tiers and the
+// cumulative revenue share curve.
+func (h *InsightHandler) GetCustomerPareto(c *gin.Context) {
+	snap := h.snapshot()
+	h.servePareto(c, snap.version, snap.data.CustomerPareto)
+}
+
+// servePareto writes a paginated Pareto classification.
+// Query parameters:
+// - a, b: tier A and B thresholds in percent of revenue (defaults 80, 15)
+// - tier: only return items in tier A, B or C (optional)
+// - points: number of points on the cumulative curve (default 100)
+// - limit: number of records to return (default 100)
+// - offset: starting position in the dataset (default 0)
+// - cursor: next_cursor from the previous page, used instead of offset
+func (h *InsightHandler) servePareto(c *gin.Context, version uint64, p *services.Pareto) {
+	if p == nil {
+		writePage(c, version, []models.ParetoItem{}, nil)
+		return
+	}
+	a, errA := strconv.ParseFloat(c.DefaultQuery("a", strconv.FormatFloat(p.DefaultA, 'f', -1, 64)), 64)
+	b, errB := strconv.ParseFloat(c.DefaultQuery("b", strconv.FormatFloat(p.DefaultB, 'f', -1, 64)), 64)
+	// Written so that NaN fails every comparison
+	if errA != nil || errB != nil || !(a > 0 && b >= 0 && a+b <= 100) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "thresholds must satisfy a > 0, b >= 0 and a + b <= 100"})
+		return
+	}
+	tier := c.Query("tier")
+	if tier != "" && tier != "A" && tier != "B" && tier != "C" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid tier: " + tier})
+		return
+	}
+	points, err := strconv.Atoi(c.DefaultQuery("points", "100"))
+	if err != nil || points < 1 || points > 1000 {
+		points = 100
+	}
+
+	lp, ok := parseList[models.ParetoItem](c)
+	if !ok {
+		return
+	}
+	extra := gin.H{
+		"thresholds": gin.H{"a": a, "b": b, "c": 100 - a - b},
+		"tiers":      p.Tiers(a, b),
+		"curve":      p.Curve(points),
+	}
+	if len(lp.keys) > 0 || lp.format != "" {
+		// Re-sorting and exports need every matching item, not just the
+		// ranked page
+		_, all := p.Page(a, b, tier, 0, math.MaxInt)
+		writePage(c, version, all, extra)
+		return
+	}
+	limit, offset, ok := pageStart(c, version, defaultLimit)
+	if !ok {
+		return
+	}
+	total, page := p.Page(a, b, tier, offset, limit)
+	extra["total"] = total
+	extra["data"] = lp.project(page)
+	extra["next_cursor"] = cursorValue(nextCursor(c, version, offset+len(page), total))
+	c.JSON(http.StatusOK, extra)
+}
+
+// GetProductPrices handles GET requests to return paginated average, min and
+// max selling prices per product, best sellers first.
+// Query parameters:
+// - limit: number of records to return (default 100)
+// - offset: starting position in the dataset (default 0)
+// - cursor: next_cursor from the previous page, used instead of offset
+func (h *InsightHandler) GetProductPrices(c *gin.Context) {
+	snap := h.snapshot()
+	writePage(c, snap.version, snap.data.ProductPrices, nil)
+}
+
+// GetCategoryPrices returns average, min and max selling prices per category.
+func (h *InsightHandler) GetCategoryPrices(c *gin.Context) {
+	snap := h.snapshot()
+	writeList(c, snap.data.CategoryPrices)
+}
+
+// GetPriceChanges handles GET requests to return paginated month-over-month
+// changes in average selling price with the sales volume either side.
+// Query parameters:
+// - product_id: only return changes for this product (optional)
+// - limit: number of records to return (default 100)
+// - offset: starting position in the dataset (default 0)
+// - cursor: next_cursor from the previous page, used instead of offset
+func (h *InsightHandler) GetPriceChanges(c *gin.Context) {
+	snap := h.snapshot()
+	all := snap.data.PriceChanges
+	if id := c.Query("product_id"); id != "" {
+		out := make([]models.PriceChange, 0)
+		for _, row := range all {
+			if row.ProductID == id {
+				out = append(out, row)
+			}
+		}
+		all = out
+	}
+	writePage(c, snap.version, all, nil)
+}
+
+// GetPriceBands returns a histogram of transactions, units and revenue by
+// unit price band.
+// Query parameters:
+// - category: only count this category (optional)
+func (h *InsightHandler) GetPriceBands(c *gin.Context) {
+	snap := h.snapshot()
+	bands, ok := snap.data.PriceBands[c.Query("category")]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "category not found"})
+		return
+	}
+	writeList(c, bands)
+}
+
+// GetDistributions returns order value or quantity distributions (mean,
+// p50/p90/p99 and a histogram) for each member of a dimension.
+// Query parameters:
+// - dimension: total (default), country, region or month
+// - metric: order_value (default) or quantity
+// - bins: number of histogram bins (default 10, max 100)
+func (h *InsightHandler) GetDistributions(c *gin.Context) {
+	snap := h.snapshot()
+	bins, err := strconv.Atoi(c.DefaultQuery("bins", "10"))
+	if err != nil || bins < 1 || bins > 100 {
+		bins = 10
+	}
+	dims, ok := snap.data.Distributions.Distributions(c.DefaultQuery("dimension", "total"), c.DefaultQuery("metric", "order_value"), bins)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "dimension must be one of total, country, region, month and metric one of order_value, quantity"})
+		return
+	}
+	writeList(c, dims)
+}
+
+// GetAnomalies returns periods where revenue or units of a series deviate
+// from the rolling mean of the preceding periods, most extreme first.
+// Query parameters:
+// - dimension: total, country, region or category (default all)
+// - key: only scan this country, region or category (optional)
+// - granularity: day (default) or month
+// - metric: revenue (default) or units
+// - window: baseline length in periods (default 28 days or 6 months)
+// - z: minimum absolute z-score (default 3)
+// - severity: only return low, medium or high anomalies (optional)
+// - limit, offset, cursor: pagination (default 100, 0)
+func (h *InsightHandler) GetAnomalies(c *gin.Context) {
+	snap := h.snapshot()
+	series := snap.data.Series
+	if series == nil {
+		writePage(c, snap.version, []models.Anomaly{}, nil)
+		return
+	}
+	q := services.AnomalyQuery{
+		Dimension:   c.Query("dimension"),
+		Key:         c.Query("key"),
+		Granularity: c.DefaultQuery("granularity", services.GranularityDay),
+		Metric:      c.DefaultQuery("metric", services.MetricRevenue),
+		Window:      series.DayWindow,
+		Threshold:   series.Threshold,
+	}
+	if q.Granularity == services.GranularityMonth {
+		q.Window = series.MonthWindow
+	}
+	if w, err := strconv.Atoi(c.Query("window")); err == nil && w >= 2 {
+		q.Window = w
+	}
+	if z, err := strconv.ParseFloat(c.Query("z"), 64); err == nil && z > 0 {
+		q.Threshold = z
+	}
+
+	all, ok := series.Anomalies(q)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "dimension must be one of total, country, region, category; granularity day or month; metric revenue or units"})
+		return
+	}
+	if sev := c.Query("severity"); sev != "" {
+		out := make([]models.Anomaly, 0)
+		for _, a := range all {
+			if a.Severity == sev {
+				out = append(out, a)
+			}
+		}
+		all = out
+	}
+	writePage(c, snap.version, all, nil)
+}
+
+// GetSeries returns a daily or monthly revenue or units series, zero-filled
+// between its first and last sale.
+// Query parameters:
+// - dimension: total (default), country, region or category
+// - key: the country, region or category (required unless dimension is total)
+// - granularity: month (default) or day
+// - metric: revenue (default) or units
+// - rolling: add an N-period moving average (optional)
+// - cumulative: add year-to-date and running totals when true (optional)
+func (h *InsightHandler) GetSeries(c *gin.Context) {
+	snap := h.snapshot()
+	dim := c.DefaultQuery("dimension", "total")
+	key := c.Query("key")
+	if dim == "total" {
+		key = "all"
+	}
+	granularity := c.DefaultQuery("granularity", services.GranularityMonth)
+	metric := c.DefaultQuery("metric", services.MetricRevenue)
+	if (granularity != services.GranularityDay && granularity != services.GranularityMonth) ||
+		(metric != services.MetricRevenue && metric != services.MetricUnits) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "granularity must be day or month and metric revenue or units"})
+		return
+	}
+	if snap.data.Series == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "series not found"})
+		return
+	}
+	labels, values, ok := snap.data.Series.Series(dim, key, granularity, metric)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "series not found"})
+		return
+	}
+
+	rolling, cumulative := seriesParams(c)
+	stats := services.DeriveSeries(labels, values, rolling, cumulative)
+	out := make([]models.SeriesPoint, len(values))
+	for i := range values {
+		out[i] = models.SeriesPoint{Period: labels[i], Value: values[i], SeriesStats: stats[i]}
+	}
+	writeList(c, out)
+}
+
+// GetRollup returns revenue, units and transactions for a hierarchy at
+// every level of aggregation, including subtotals and the grand total.
+// Each row's grouping marks which levels are rolled up.
+// Query parameters:
+// - hierarchy: geo (country, region, product; default) or time (year, month)
+// - cube: return every combination of levels instead of the rollup when true
+// - limit, offset, cursor: pagination (default 100, 0)
+func (h *InsightHandler) GetRollup(c *gin.Context) {
+	snap := h.snapshot()
+	hierarchy := c.DefaultQuery("hierarchy", "geo")
+	cube, _ := strconv.ParseBool(c.Query("cube"))
+	extra := gin.H{"levels": services.RollupHierarchies[hierarchy]}
+	if snap.data.Cube == nil {
+		writePage(c, snap.version, []models.RollupRow{}, extra)
+		return
+	}
+	all, ok := snap.data.Cube.Rollup(hierarchy, cube)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "hierarchy must be geo or time"})
+		return
+	}
+	writePage(c, snap.version, all, extra)
+}
+
+// GetForecast returns a monthly revenue or units forecast with confidence
+// intervals and backtest accuracy for the total or one dimension member.
+// Query parameters:
+// - dimension: total (default), country, region or category
+// - key: the country, region or category (required unless dimension is total)
+// - metric: revenue (default) or units
+// - horizon: months to forecast (default 6, max 36)
+// - level: confidence level in percent (default 95, 50-99.9)
+func (h *InsightHandler) GetForecast(c *gin.Context) {
+	snap := h.snapshot()
+	q := services.ForecastQuery{
+		Dimension: c.DefaultQuery("dimension", "total"),
+		Key:       c.Query("key"),
+		Metric:    c.DefaultQuery("metric", services.MetricRevenue),
+		Horizon:   6,
+		Level:     95,
+	}
+	if q.Dimension == "total" {
+		q.Key = "all"
+	}
+	if n, err := strconv.Atoi(c.Query("horizon")); err == nil && n >= 1 && n <= 36 {
+		q.Horizon = n
+	}
+	if l, err := strconv.ParseFloat(c.Query("level"), 64); err == nil && l >= 50 && l <= 99.9 {
+		q.Level = l
+	}
+	if q.Metric != services.MetricRevenue && q.Metric != services.MetricUnits {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "metric must be revenue or units"})
+		return
+	}
+
+	if snap.data.Series == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "series not found"})
+		return
+	}
+	f, ok := snap.data.Series.Forecast(q)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "series not found"})
+		return
+	}
+	writeObject(c, f)
+}
+
+// GetCategoryRevenue returns revenue, units, transactions, distinct products
+// and revenue share for each category.
+func (h *InsightHandler) GetCategoryRevenue(c *gin.Context) {
+	snap := h.snapshot()
+	writeList(c, snap.data.CategoryRevenue)
+}
+
+// GetCategoryMonthly returns revenue and units by category and month.
+// Query parameters:
+// - category: only return rows for this category (optional)
+// - rolling: add an N-month moving average of revenue per category (optional)
+// - cumulative: add year-to-date and running revenue totals when true (optional)
+func (h *InsightHandler) GetCategoryMonthly(c *gin.Context) {
+	snap := h.snapshot()
+	all := snap.data.CategoryMonthly
+	if cat := c.Query("category"); cat != "" {
+		out := make([]models.CategoryMonthly, 0)
+		for _, row := range all {
+			if row.Category == cat {
+				out = append(out, row)
+			}
+		}
+		all = out
+	}
+
+	rolling, cumulative := seriesParams(c)
+	if rolling == 0 && !cumulative {
+		writeList(c, all)
+		return
+	}
+
+	// Rows are sorted by category then month, so each category is a run
+	rows := append([]models.CategoryMonthly(nil), all...)
+	for start := 0; start < len(rows); {
+		end := start
+		for end < len(rows) && rows[end].Category == rows[start].Category {
+			end++
+		}
+		labels := make([]string, 0, end-start)
+		values := make([]float64, 0, end-start)
+		for _, row := range rows[start:end] {
+			labels = append(labels, row.Month)
+			values = append(values, row.TotalRevenue)
+		}
+		for i, s := range services.DeriveSeries(labels, values, rolling, cumulative) {
+			rows[start+i].SeriesStats = s
+		}
+		start = end
+	}
+	writeList(c, rows)
+}
+
+// GetCategoryRegions returns revenue and units by category and region.
+// Query parameters:
+// - category: only return rows for this category (optional)
+func (h *InsightHandler) GetCategoryRegions(c *gin.Context) {
+	snap := h.snapshot()
+	all := snap.data.CategoryRegions
+	if cat := c.Query("category"); cat != "" {
+		out := make([]models.CategoryRegion, 0)
+		for _, row := range all {
+			if row.Category == cat {
+				out = append(out, row)
+			}
+		}
+		all = out
+	}
+	writeList(c, all)
 }
@@ -0,0 +1,282 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// jsonField is a struct field addressed by its JSON name
+type jsonField struct {
+	name  string
+	index []int
+	kind  reflect.Kind // after dereferencing pointers
+}
+
+// jsonFields lists the JSON-visible fields of struct type t, flattening
+// embedded structs the way encoding/json does
+func jsonFields(t reflect.Type) []jsonField {
+	var out []jsonField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := strings.Split(f.Tag.Get("json"), ",")[0]
+		if tag == "-" || (!f.IsExported() && !f.Anonymous) {
+			continue
+		}
+		if f.Anonymous && tag == "" && f.Type.Kind() == reflect.Struct {
+			for _, sub := range jsonFields(f.Type) {
+				sub.index = append([]int{i}, sub.index...)
+				out = append(out, sub)
+			}
+			continue
+		}
+		if tag == "" {
+			tag = f.Name
+		}
+		ft := f.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		out = append(out, jsonField{name: tag, index: []int{i}, kind: ft.Kind()})
+	}
+	return out
+}
+
+// sortable reports whether values of kind k can be compared
+func sortable(k reflect.Kind) bool {
+	switch k {
+	case reflect.String, reflect.Bool, reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// sortKey is one validated sort=field:direction term
+type sortKey struct {
+	jsonField
+	desc bool
+}
+
+// listParams holds the validated sort, fields and export format query
+// parameters for a list of T
+type listParams[T any] struct {
+	keys   []sortKey
+	fields []jsonField // nil keeps every field
+	format string      // csv, xlsx or ndjson to export, "" for JSON
+}
+
+// parseList validates sort=field[:asc|desc],... and fields=a,b against the
+// JSON fields of T and resolves the export format. On error it writes a 400
+// and returns false.
+func parseList[T any](c *gin.Context) (listParams[T], bool) {
+	var lp listParams[T]
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	byName := make(map[string]jsonField)
+	names := make([]string, 0)
+	for _, f := range jsonFields(t) {
+		byName[f.name] = f
+		names = append(names, f.name)
+	}
+	fail := func(msg string) (listParams[T], bool) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg, "fields": names})
+		return lp, false
+	}
+
+	format, err := exportFormat(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return lp, false
+	}
+	lp.format = format
+
+	if s := c.Query("sort"); s != "" {
+		for _, term := range strings.Split(s, ",") {
+			name, dir, _ := strings.Cut(strings.TrimSpace(term), ":")
+			f, ok := byName[name]
+			if !ok {
+				return fail("unknown sort field: " + name)
+			}
+			if !sortable(f.kind) {
+				return fail("cannot sort by " + name)
+			}
+			switch dir {
+			case "", "asc":
+				lp.keys = append(lp.keys, sortKey{jsonField: f})
+			case "desc":
+				lp.keys = append(lp.keys, sortKey{jsonField: f, desc: true})
+			default:
+				return fail(fmt.Sprintf("invalid sort direction %q for %s", dir, name))
+			}
+		}
+	}
+	if s := c.Query("fields"); s != "" {
+		for _, name := range strings.Split(s, ",") {
+			f, ok := byName[strings.TrimSpace(name)]
+			if !ok {
+				return fail("unknown field: " + strings.TrimSpace(name))
+			}
+			lp.fields = append(lp.fields, f)
+		}
+	}
+	return lp, true
+}
+
+// sort returns rows ordered by the sort keys. The sort is stable, so rows
+// equal on every key keep the endpoint's default (deterministic) order.
+// Without keys rows are returned unchanged.
+func (lp listParams[T]) sort(rows []T) []T {
+	if len(lp.keys) == 0 {
+		return rows
+	}
+	out := append([]T(nil), rows...)
+	sort.SliceStable(out, func(i, j int) bool {
+		a, b := reflect.ValueOf(&out[i]).Elem(), reflect.ValueOf(&out[j]).Elem()
+		for _, k := range lp.keys {
+			if c := compareValues(a.FieldByIndex(k.index), b.FieldByIndex(k.index)); c != 0 {
+				return (c < 0) != k.desc
+			}
+		}
+		return false
+	})
+	return out
+}
+
+// compareValues compares two values of the same sortable kind, treating a
+// nil pointer as smaller than any value
+func compareValues(a, b reflect.Value) int {
+	if a.Kind() == reflect.Pointer {
+		switch {
+		case a.IsNil() && b.IsNil():
+			return 0
+		case a.IsNil():
+			return -1
+		case b.IsNil():
+			return 1
+		}
+		a, b = a.Elem(), b.Elem()
+	}
+	switch a.Kind() {
+	case reflect.String:
+		return strings.Compare(a.String(), b.String())
+	case reflect.Bool:
+		switch {
+		case a.Bool() == b.Bool():
+			return 0
+		case b.Bool():
+			return -1
+		}
+		return 1
+	case reflect.Float32, reflect.Float64:
+		return cmpOrdered(a.Float(), b.Float())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return cmpOrdered(a.Uint(), b.Uint())
+	default:
+		return cmpOrdered(a.Int(), b.Int())
+	}
+}
+
+// cmpOrdered returns -1, 0 or 1 as a is less than, equal to or greater than b
+func cmpOrdered[V int64 | uint64 | float64](a, b V) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// project reduces rows to the selected fields, or returns them unchanged
+// when no fields were requested
+func (lp listParams[T]) project(rows []T) any {
+	if lp.fields == nil {
+		return rows
+	}
+	out := make([]map[string]any, len(rows))
+	for i := range rows {
+		v := reflect.ValueOf(&rows[i]).Elem()
+		m := make(map[string]any, len(lp.fields))
+		for _, f := range lp.fields {
+			m[f.name] = v.FieldByIndex(f.index).Interface()
+		}
+		out[i] = m
+	}
+	return out
+}
+
+// exported writes every row in the requested export format and reports
+// whether it did; it returns false for JSON responses
+func (lp listParams[T]) exported(c *gin.Context, rows []T) bool {
+	if lp.format == "" {
+		return false
+	}
+	exportRows(c, lp.format, lp.sort(rows), lp.fields)
+	return true
+}
+
+// writeList writes rows as a JSON array after applying the sort and fields
+// query parameters, or exports them
+func writeList[T any](c *gin.Context, rows []T) {
+	lp, ok := parseList[T](c)
+	if !ok || lp.exported(c, rows) {
+		return
+	}
+	c.JSON(http.StatusOK, lp.project(lp.sort(rows)))
+}
+
+// writeObject writes v as JSON, or exports it as a single row with the
+// selected fields
+func writeObject[T any](c *gin.Context, v T) {
+	lp, ok := parseList[T](c)
+	if !ok {
+		return
+	}
+	if lp.format != "" {
+		exportObject(c, lp.format, v, lp.fields)
+		return
+	}
+	c.JSON(http.StatusOK, v)
+}
+
+// writePage sorts rows from the given snapshot version, paginates them with
+// limit and offset or cursor and projects the page, writing {"total",
+// "data", "next_cursor"} merged into extra (which may be nil)
+func writePage[T any](c *gin.Context, version uint64, rows []T, extra gin.H) {
+	writePageLimit(c, version, rows, extra, defaultLimit)
+}
+
+// writePageLimit is writePage with a different default page size
+// Exports ignore pagination and include every row.
+func writePageLimit[T any](c *gin.Context, version uint64, rows []T, extra gin.H, defLimit int) {
+	lp, ok := parseList[T](c)
+	if !ok || lp.exported(c, rows) {
+		return
+	}
+	rows = lp.sort(rows)
+	total := len(rows)
+	offset, end, next, ok := pageWindow(c, version, total, defLimit)
+	if !ok {
+		return
+	}
+	if extra == nil {
+		extra = gin.H{}
+	}
+	extra["total"] = total
+	extra["data"] = lp.project(rows[offset:end])
+	extra["next_cursor"] = cursorValue(next)
+	c.JSON(http.StatusOK, extra)
+}
+
+// cursorValue renders an empty cursor as JSON null
+func cursorValue(next string) any {
+	if next == "" {
+		return nil
+	}
+	return next
+}
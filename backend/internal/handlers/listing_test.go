@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/GimhaniHM/backend/internal/models"
+	"github.com/GimhaniHM/backend/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func listRouter() *gin.Engine {
+	h := NewInsightHandler(services.Insights{
+		CountryRevenue: []models.CountryRevenue{
+			{Country: "USA", ProductID: "p1", TotalRevenue: 100, TransactionCount: 1},
+			{Country: "France", ProductID: "p2", TotalRevenue: 300, TransactionCount: 2},
+			{Country: "USA", ProductID: "p3", TotalRevenue: 200, TransactionCount: 2},
+		},
+	})
+	router := gin.New()
+	router.GET("/api/revenue/countries", h.GetCountryRevenue)
+	return router
+}
+
+func TestListSortAndFields(t *testing.T) {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/revenue/countries?sort=transaction_count:desc,country&fields=product_id&limit=2", nil)
+	listRouter().ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var got struct {
+		Total int              `json:"total"`
+		Data  []map[string]any `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+	assert.Equal(t, 3, got.Total)
+	assert.Equal(t, []map[string]any{{"product_id": "p2"}, {"product_id": "p3"}}, got.Data)
+}
+
+func TestListRejectsUnknownField(t *testing.T) {
+	for _, q := range []string{"sort=revenue", "sort=country:up", "fields=country,nope"} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/revenue/countries?"+q, nil)
+		listRouter().ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, q)
+		assert.Contains(t, w.Body.String(), "total_revenue", q)
+	}
+}
+
+func TestListCursor(t *testing.T) {
+	router := listRouter()
+	get := func(url string) (int, map[string]any) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", url, nil)
+		router.ServeHTTP(w, req)
+		var body map[string]any
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		return w.Code, body
+	}
+
+	code, first := get("/api/revenue/countries?sort=total_revenue:desc&limit=2")
+	assert.Equal(t, http.StatusOK, code)
+	next, ok := first["next_cursor"].(string)
+	assert.True(t, ok)
+
+	code, second := get("/api/revenue/countries?sort=total_revenue:desc&limit=2&cursor=" + next)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, second["data"], 1)
+	assert.Equal(t, "p1", second["data"].([]any)[0].(map[string]any)["product_id"])
+	assert.Nil(t, second["next_cursor"])
+
+	// A cursor only continues the query it was issued for
+	code, _ = get("/api/revenue/countries?sort=country&limit=2&cursor=" + next)
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = get("/api/revenue/countries?cursor=garbage")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestListHugeLimit(t *testing.T) {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/revenue/countries?offset=1&limit=9223372036854775807", nil)
+	listRouter().ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var got struct {
+		Data []map[string]any `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+	assert.Len(t, got.Data, 2)
+}
+
+func TestListCursorExpiresOnReload(t *testing.T) {
+	h := NewInsightHandler(services.Insights{
+		CountryRevenue: []models.CountryRevenue{{Country: "USA"}, {Country: "France"}},
+	})
+	router := gin.New()
+	router.GET("/api/revenue/countries", h.GetCountryRevenue)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/revenue/countries?limit=1", nil)
+	router.ServeHTTP(w, req)
+	var body struct {
+		NextCursor string `json:"next_cursor"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+
+	h.Reload(services.Insights{})
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/revenue/countries?limit=1&cursor="+body.NextCursor, nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusGone, w.Code)
+	assert.Contains(t, w.Body.String(), "expired")
+}
+
+func TestEmptySnapshotLists(t *testing.T) {
+	h := NewInsightHandler(services.Insights{})
+	router := gin.New()
+	router.GET("/api/products/pairs/top", h.GetTopPairs)
+	router.GET("/api/pareto/products", h.GetProductPareto)
+	for _, url := range []string{"/api/products/pairs/top", "/api/pareto/products"} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", url, nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code, url)
+		assert.Contains(t, w.Body.String(), `"total":0`, url)
+	}
+}
+
+func TestParetoRejectsNaN(t *testing.T) {
+	router := productsRouter(t)
+	for _, q := range []string{"a=NaN", "b=NaN", "a=Inf", "b=-Inf"} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/pareto/products?"+q, nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code, q)
+	}
+}
+
+// productsRouter serves product search, top pairs, related products and the
+// product Pareto over a small CSV. P1 "Ball" (revenue 30) and P2 "Ball Pro"
+// (revenue 20) share two baskets; P3 "Bat" shares one with each.
+func productsRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "tx.csv")
+	content := "transaction_id,transaction_date,user_id,country,region,product_id,product_name,category,price,quantity,total_price,stock_quantity,added_date\n" +
+		"T1,2024-01-05,U1,USA,West,P1,Ball,Toys,10,1,10,5,2023-12-01\n" +
+		"T2,2024-01-05,U1,USA,West,P2,Ball Pro,Toys,10,1,10,5,2023-12-01\n" +
+		"T3,2024-01-06,U2,USA,West,P1,Ball,Toys,10,2,20,5,2023-12-01\n" +
+		"T4,2024-01-06,U2,USA,West,P2,Ball Pro,Toys,10,1,10,5,2023-12-01\n" +
+		"T5,2024-01-06,U2,USA,West,P3,Bat,Toys,5,1,5,5,2023-12-01\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	opts := services.DefaultOptions()
+	opts.BasketMinCount = 1
+	ins, err := services.NewConcurrentAggregator(path, 2).WithOptions(opts).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := NewInsightHandler(ins)
+	router := gin.New()
+	router.GET("/api/products/search", h.SearchProducts)
+	router.GET("/api/products/pairs/top", h.GetTopPairs)
+	router.GET("/api/products/:id/related", h.GetRelatedProducts)
+	router.GET("/api/pareto/products", h.GetProductPareto)
+	return router
+}
+
+func TestLimitedListsSortBeforeLimit(t *testing.T) {
+	router := productsRouter(t)
+	for url, want := range map[string]map[string]any{
+		"/api/products/search?q=ball&limit=1&sort=total_revenue:asc&fields=product_id": {"product_id": "P2"},
+		"/api/products/pairs/top?limit=1&sort=baskets&fields=product_a,product_b":      {"product_a": "P1", "product_b": "P3"},
+		"/api/products/P1/related?limit=1&sort=confidence&fields=product_id":           {"product_id": "P3"},
+	} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", url, nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code, url)
+		var got struct {
+			Data []map[string]any `json:"data"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &got), url)
+		assert.Equal(t, []map[string]any{want}, got.Data, url)
+	}
+}
+
+func TestLimitedListsCursor(t *testing.T) {
+	router := productsRouter(t)
+	for url, want := range map[string]int{
+		"/api/products/search?q=b&limit=1": 3,
+		"/api/products/pairs/top?limit=1":  3,
+		"/api/products/P1/related?limit=1": 2,
+	} {
+		// Following next_cursor visits every row, one per page
+		seen, next := 0, ""
+		for {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", url+"&cursor="+next, nil)
+			router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusOK, w.Code, url)
+
+			var body struct {
+				Total      int              `json:"total"`
+				Data       []map[string]any `json:"data"`
+				NextCursor string           `json:"next_cursor"`
+			}
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body), url)
+			assert.Equal(t, want, body.Total, url)
+			seen += len(body.Data)
+			if next = body.NextCursor; next == "" || seen > want {
+				break
+			}
+		}
+		assert.Equal(t, want, seen, url)
+	}
+}
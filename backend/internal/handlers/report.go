@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+
+	"github.com/GimhaniHM/backend/internal/models"
+	"github.com/GimhaniHM/backend/internal/services"
+)
+
+// reports maps each insight WriteReport can produce to its full result set,
+// in the same order and with the same default parameters as the matching
+// endpoint. Per-ID lookups (product detail and search, related products,
+// stock history) and the country and region drill-downs are left out: they
+// answer one member at a time, and their rows are already covered by the
+// products, pairs, inventory, country-revenue, regions and rollup reports.
+var reports = map[string]func(ins services.Insights) any{
+	"country-revenue":  func(ins services.Insights) any { return ins.CountryRevenue },
+	"products":         func(ins services.Insights) any { return ins.TopProducts },
+	"monthly-sales":    func(ins services.Insights) any { return ins.MonthlySales },
+	"regions":          func(ins services.Insights) any { return ins.RegionRevenue },
+	"countries":        func(ins services.Insights) any { return ins.Countries },
+	"categories":       func(ins services.Insights) any { return ins.CategoryRevenue },
+	"category-monthly": func(ins services.Insights) any { return ins.CategoryMonthly },
+	"category-regions": func(ins services.Insights) any { return ins.CategoryRegions },
+	"segments":         func(ins services.Insights) any { return ins.CustomerSegments },
+	"cohorts":          func(ins services.Insights) any { return ins.Cohorts },
+	"pairs":            func(ins services.Insights) any { return ins.Baskets.TopPairs(math.MaxInt) },
+	"inventory":        func(ins services.Insights) any { return ins.Inventory },
+	"lifecycle":        func(ins services.Insights) any { return ins.ProductLifecycle },
+	"launches":         func(ins services.Insights) any { return ins.LaunchCohorts },
+	"product-pareto":   func(ins services.Insights) any { return paretoItems(ins.ProductPareto) },
+	"customer-pareto":  func(ins services.Insights) any { return paretoItems(ins.CustomerPareto) },
+	"product-prices":   func(ins services.Insights) any { return ins.ProductPrices },
+	"category-prices":  func(ins services.Insights) any { return ins.CategoryPrices },
+	"price-changes":    func(ins services.Insights) any { return ins.PriceChanges },
+	"price-bands":      func(ins services.Insights) any { return ins.PriceBands[""] },
+	"customers":        func(ins services.Insights) any { return customerProfiles(ins.Customers) },
+	"distributions":    func(ins services.Insights) any { return distributions(ins.Distributions) },
+	"series":           func(ins services.Insights) any { return totalSeries(ins.Series) },
+	"anomalies":        func(ins services.Insights) any { return anomalies(ins.Series) },
+	"forecast":         func(ins services.Insights) any { return forecast(ins.Series) },
+	"rollup":           func(ins services.Insights) any { return rollup(ins.Cube, "geo") },
+	"rollup-time":      func(ins services.Insights) any { return rollup(ins.Cube, "time") },
+	"ingest":           func(ins services.Insights) any { return ins.Ingest },
+}
+
+// paretoItems returns every item of a Pareto classification with its
+// default tiers
+func paretoItems(p *services.Pareto) []models.ParetoItem {
+	if p == nil {
+		return []models.ParetoItem{}
+	}
+	_, items := p.Page(p.DefaultA, p.DefaultB, "", 0, math.MaxInt)
+	return items
+}
+
+// customerProfiles returns every customer's RFM profile by user ID
+func customerProfiles(m map[string]models.CustomerProfile) []models.CustomerProfile {
+	out := make([]models.CustomerProfile, 0, len(m))
+	for _, p := range m {
+		out = append(out, p)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].UserID < out[j].UserID })
+	return out
+}
+
+// distributions returns the overall order value distribution
+func distributions(d *services.DistributionIndex) []models.Distribution {
+	dims, _ := d.Distributions("total", "order_value", 10)
+	return dims
+}
+
+// totalSeries returns total monthly revenue
+func totalSeries(s *services.SeriesIndex) []models.SeriesPoint {
+	if s == nil {
+		return nil
+	}
+	labels, values, _ := s.Series("total", "all", services.GranularityMonth, services.MetricRevenue)
+	out := make([]models.SeriesPoint, len(values))
+	for i := range values {
+		out[i] = models.SeriesPoint{Period: labels[i], Value: values[i]}
+	}
+	return out
+}
+
+// anomalies returns daily revenue anomalies across every dimension
+func anomalies(s *services.SeriesIndex) []models.Anomaly {
+	if s == nil {
+		return nil
+	}
+	all, _ := s.Anomalies(services.AnomalyQuery{
+		Granularity: services.GranularityDay,
+		Metric:      services.MetricRevenue,
+		Window:      s.DayWindow,
+		Threshold:   s.Threshold,
+	})
+	return all
+}
+
+// forecast returns the six-month total revenue forecast at 95% confidence
+func forecast(s *services.SeriesIndex) models.Forecast {
+	if s == nil {
+		return models.Forecast{}
+	}
+	f, _ := s.Forecast(services.ForecastQuery{Dimension: "total", Key: "all", Metric: services.MetricRevenue, Horizon: 6, Level: 95})
+	return f
+}
+
+// rollup returns every level of a hierarchy with subtotals
+func rollup(c *services.CubeIndex, hierarchy string) []models.RollupRow {
+	if c == nil {
+		return nil
+	}
+	rows, _ := c.Rollup(hierarchy, false)
+	return rows
+}
+
+// ReportNames lists the insights accepted by WriteReport
+func ReportNames() []string {
+	names := make([]string, 0, len(reports))
+	for name := range reports {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// WriteReport writes the full result set of one insight to w as json, csv,
+// xlsx or ndjson, the same formats the HTTP endpoints export
+func WriteReport(ctx context.Context, w io.Writer, ins services.Insights, name, format string) error {
+	rows, ok := reports[name]
+	if !ok {
+		return fmt.Errorf("unknown insight %q", name)
+	}
+	v := reflect.ValueOf(rows(ins))
+	if v.Kind() == reflect.Slice && v.IsNil() {
+		v = reflect.MakeSlice(v.Type(), 0, 0) // [] rather than null in JSON
+	}
+
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v.Interface())
+	case formatCSV, formatXLSX, formatNDJSON:
+	default:
+		return fmt.Errorf("format must be json, csv, xlsx or ndjson")
+	}
+	if v.Kind() == reflect.Struct {
+		return writeTables(ctx, w, format, objectTables(name, v, nil, format))
+	}
+	return writeTables(ctx, w, format, []table{rowsTable(name, v, nil)})
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/GimhaniHM/backend/internal/models"
+	"github.com/GimhaniHM/backend/internal/services"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteReport(t *testing.T) {
+	ins := services.Insights{
+		RegionRevenue: []models.RegionRevenue{{Region: "West", TotalRevenue: 20, ItemsSold: 2, UniqueCustomers: 1}},
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(t, WriteReport(context.Background(), &buf, ins, "regions", "csv"))
+	assert.Equal(t, "region,total_revenue,items_sold,unique_customers\nWest,20,2,1\n", buf.String())
+
+	buf.Reset()
+	assert.NoError(t, WriteReport(context.Background(), &buf, ins, "cohorts", "json"))
+	assert.Equal(t, "[]\n", buf.String())
+
+	assert.Error(t, WriteReport(context.Background(), &buf, ins, "nope", "csv"))
+	assert.Error(t, WriteReport(context.Background(), &buf, ins, "regions", "pdf"))
+}
+
+// TestWriteReportEveryInsight checks each insight writes in every format,
+// from both an aggregated CSV and an empty snapshot
+func TestWriteReportEveryInsight(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tx.csv")
+	content := "transaction_id,transaction_date,user_id,country,region,product_id,product_name,category,price,quantity,total_price,stock_quantity,added_date\n" +
+		"T1,2024-01-05,U1,USA,West,P1,Ball,Toys,10,1,10,5,2023-12-01\n" +
+		"T2,2024-02-06,U2,France,North,P2,Bat,Toys,5,2,10,5,2023-12-01\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	full, err := services.NewConcurrentAggregator(path, 2).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, ins := range []services.Insights{full, {}} {
+		for _, name := range ReportNames() {
+			for _, format := range []string{"json", "csv", "xlsx", "ndjson"} {
+				var buf bytes.Buffer
+				assert.NoError(t, WriteReport(context.Background(), &buf, ins, name, format), name+" "+format)
+			}
+		}
+	}
+	var buf bytes.Buffer
+	assert.NoError(t, WriteReport(context.Background(), &buf, full, "customers", "csv"))
+	assert.Contains(t, buf.String(), "\nU1,")
+}
@@ -39,9 +39,9 @@ func (h *RevenueHandler) GetCountryRevenue(c *gin.Context) {
 	if offset > total {
 		offset = total
 	}
-	end := offset + limit
-	if end > total {
-		end = total
+	end := total
+	if limit < total-offset {
+		end = offset + limit
 	}
 
 	page := all[offset:end]
@@ -22,27 +22,439 @@ type Transaction struct {
 // CountryRevenue for /api/revenue/countries
 type CountryRevenue struct {
 	Country          string  `json:"country"`
-	ProductName      string  `json:"product_name"`
+	ProductID        string  `json:"product_id"`
+	ProductName      string  `json:"product_name"` // latest name seen for the ID
+	Category         string  `json:"category"`
 	TotalRevenue     float64 `json:"total_revenue"`
 	TransactionCount int     `json:"transaction_count"`
 }
 
 // ProductFrequency for /api/products/top
 type ProductFrequency struct {
-	ProductName   string `json:"product_name"`
+	ProductID     string `json:"product_id"`
+	ProductName   string `json:"product_name"` // latest name seen for the ID
+	Category      string `json:"category"`
 	PurchaseCount int    `json:"purchase_count"`
 	StockQuantity int    `json:"stock_quantity"`
+	// MaxError is how far PurchaseCount may overcount in approximate top-K
+	// mode; omitted when the count is exact
+	MaxError int `json:"max_error,omitempty"`
 }
 
 // MonthlySales for /api/sales/monthly
 type MonthlySales struct {
-	Month       string `json:"month"`
-	SalesVolume int    `json:"sales_volume"`
+	Month           string `json:"month"`
+	SalesVolume     int    `json:"sales_volume"`
+	UniqueCustomers int    `json:"unique_customers"`
+	SeriesStats
 }
 
 // RegionRevenue for /api/regions/top
 type RegionRevenue struct {
+	Region          string  `json:"region"`
+	TotalRevenue    float64 `json:"total_revenue"`
+	ItemsSold       int     `json:"items_sold"`
+	UniqueCustomers int     `json:"unique_customers"`
+}
+
+// CountrySummary for /api/countries
+type CountrySummary struct {
+	Country          string  `json:"country"`
+	TotalRevenue     float64 `json:"total_revenue"`
+	TransactionCount int     `json:"transaction_count"`
+	ItemsSold        int     `json:"items_sold"`
+	UniqueCustomers  int     `json:"unique_customers"`
+}
+
+// CategoryRevenue for /api/categories
+type CategoryRevenue struct {
+	Category         string  `json:"category"`
+	TotalRevenue     float64 `json:"total_revenue"`
+	UnitsSold        int     `json:"units_sold"`
+	TransactionCount int     `json:"transaction_count"`
+	DistinctProducts int     `json:"distinct_products"`
+	RevenueShare     float64 `json:"revenue_share"` // fraction of total revenue (0-1)
+}
+
+// CategoryMonthly for /api/categories/monthly
+type CategoryMonthly struct {
+	Category     string  `json:"category"`
+	Month        string  `json:"month"`
+	TotalRevenue float64 `json:"total_revenue"`
+	UnitsSold    int     `json:"units_sold"`
+	SeriesStats
+}
+
+// CategoryRegion for /api/categories/regions
+type CategoryRegion struct {
+	Category     string  `json:"category"`
 	Region       string  `json:"region"`
 	TotalRevenue float64 `json:"total_revenue"`
-	ItemsSold    int     `json:"items_sold"`
+	UnitsSold    int     `json:"units_sold"`
+}
+
+// CustomerProfile for /api/customers/{id}
+type CustomerProfile struct {
+	UserID        string  `json:"user_id"`
+	FirstPurchase string  `json:"first_purchase"`
+	LastPurchase  string  `json:"last_purchase"`
+	RecencyDays   int     `json:"recency_days"`
+	Frequency     int     `json:"frequency"`
+	Monetary      float64 `json:"monetary"` // lifetime revenue
+	AvgOrderValue float64 `json:"avg_order_value"`
+	UnitsBought   int     `json:"units_bought"`
+	RScore        int     `json:"r_score"`
+	FScore        int     `json:"f_score"`
+	MScore        int     `json:"m_score"`
+	Segment       string  `json:"segment"`
+}
+
+// CustomerSegment for /api/customers/segments
+type CustomerSegment struct {
+	Segment        string  `json:"segment"`
+	Customers      int     `json:"customers"`
+	TotalRevenue   float64 `json:"total_revenue"`
+	RevenueShare   float64 `json:"revenue_share"` // fraction of total revenue (0-1)
+	AvgRecencyDays float64 `json:"avg_recency_days"`
+	AvgFrequency   float64 `json:"avg_frequency"`
+	AvgMonetary    float64 `json:"avg_monetary"`
+}
+
+// Cohort for /api/cohorts. Index N of each slice is the Nth month after
+// the cohort's first-purchase month (index 0 is the acquisition month).
+type Cohort struct {
+	Cohort    string    `json:"cohort"`    // first-purchase month, e.g. 2024-01
+	Customers int       `json:"customers"` // cohort size
+	Active    []int     `json:"active"`    // customers purchasing in month N
+	Retention []float64 `json:"retention"` // percent of cohort purchasing in month N
+	Revenue   []float64 `json:"revenue"`   // cohort revenue in month N
+}
+
+// ProductPair for /api/products/pairs/top
+type ProductPair struct {
+	ProductA     string  `json:"product_a"`
+	ProductNameA string  `json:"product_name_a"`
+	ProductB     string  `json:"product_b"`
+	ProductNameB string  `json:"product_name_b"`
+	Baskets      int     `json:"baskets"`       // baskets containing both products
+	Support      float64 `json:"support"`       // share of all baskets (0-1)
+	ConfidenceAB float64 `json:"confidence_ab"` // P(B in basket | A in basket)
+	ConfidenceBA float64 `json:"confidence_ba"` // P(A in basket | B in basket)
+	Lift         float64 `json:"lift"`
+}
+
+// ProductAssociation for /api/products/{id}/related
+type ProductAssociation struct {
+	ProductID   string  `json:"product_id"`
+	ProductName string  `json:"product_name"`
+	Baskets     int     `json:"baskets"`    // baskets containing both products
+	Support     float64 `json:"support"`    // share of all baskets (0-1)
+	Confidence  float64 `json:"confidence"` // P(this product | requested product)
+	Lift        float64 `json:"lift"`
+}
+
+// InventoryStatus for /api/inventory
+type InventoryStatus struct {
+	ProductID     string   `json:"product_id"`
+	ProductName   string   `json:"product_name"`
+	Category      string   `json:"category"`
+	CurrentStock  int      `json:"current_stock"`
+	StockAsOf     string   `json:"stock_as_of"`    // date of the latest stock reading
+	DailyVelocity float64  `json:"daily_velocity"` // recent units sold per day
+	DaysOfCover   *float64 `json:"days_of_cover"`  // null when there are no recent sales
+	Status        string   `json:"status"`         // out_of_stock, low_stock, overstock or ok
+}
+
+// StockPoint is the last stock reading and units sold in one month
+type StockPoint struct {
+	Month     string `json:"month"`
+	Stock     int    `json:"stock"`
+	AsOf      string `json:"as_of"`
+	UnitsSold int    `json:"units_sold"`
+}
+
+// InventoryDetail for /api/inventory/{id}
+type InventoryDetail struct {
+	InventoryStatus
+	Series []StockPoint `json:"series"`
+}
+
+// ProductLifecycle for /api/products/lifecycle
+type ProductLifecycle struct {
+	ProductID       string  `json:"product_id"`
+	ProductName     string  `json:"product_name"`
+	Category        string  `json:"category"`
+	AddedDate       string  `json:"added_date"`
+	AgeDays         int     `json:"age_days"` // days from added_date to the last date in the data
+	FirstSale       string  `json:"first_sale"`
+	DaysToFirstSale int     `json:"days_to_first_sale"`
+	UnitsFirst30    int     `json:"units_first_30d"`
+	UnitsFirst90    int     `json:"units_first_90d"`
+	RevenueFirst30  float64 `json:"revenue_first_30d"`
+	RevenueFirst90  float64 `json:"revenue_first_90d"`
+	TotalUnits      int     `json:"total_units"`
+	TotalRevenue    float64 `json:"total_revenue"`
+}
+
+// LaunchCohort for /api/products/launches, grouping products by the month
+// of their added_date
+type LaunchCohort struct {
+	LaunchMonth        string  `json:"launch_month"`
+	Products           int     `json:"products"`
+	AvgDaysToFirstSale float64 `json:"avg_days_to_first_sale"`
+	UnitsFirst30       int     `json:"units_first_30d"`
+	UnitsFirst90       int     `json:"units_first_90d"`
+	AvgUnitsFirst30    float64 `json:"avg_units_first_30d"` // per product
+	AvgUnitsFirst90    float64 `json:"avg_units_first_90d"` // per product
+	RevenueFirst30     float64 `json:"revenue_first_30d"`
+	RevenueFirst90     float64 `json:"revenue_first_90d"`
+	TotalRevenue       float64 `json:"total_revenue"`
+	PartialWindow      bool    `json:"partial_window"` // some launches are younger than 90 days
+}
+
+// ParetoItem is one ranked product or customer in /api/pareto/*
+type ParetoItem struct {
+	Rank            int     `json:"rank"`
+	ID              string  `json:"id"`
+	Name            string  `json:"name,omitempty"`
+	Revenue         float64 `json:"revenue"`
+	Share           float64 `json:"share"`            // fraction of total revenue (0-1)
+	CumulativeShare float64 `json:"cumulative_share"` // share of this and all higher-ranked items
+	Tier            string  `json:"tier"`             // A, B or C
+}
+
+// ParetoTier summarises one ABC tier
+type ParetoTier struct {
+	Tier         string  `json:"tier"`
+	Count        int     `json:"count"`
+	ItemShare    float64 `json:"item_share"` // fraction of all items (0-1)
+	Revenue      float64 `json:"revenue"`
+	RevenueShare float64 `json:"revenue_share"` // fraction of total revenue (0-1)
+}
+
+// CurvePoint is one point on a cumulative share (Lorenz) curve
+type CurvePoint struct {
+	ItemShare    float64 `json:"item_share"`    // top fraction of items (0-1)
+	RevenueShare float64 `json:"revenue_share"` // revenue share of those items (0-1)
+}
+
+// ProductPrice for /api/prices/products
+type ProductPrice struct {
+	ProductID       string  `json:"product_id"`
+	ProductName     string  `json:"product_name"`
+	Category        string  `json:"category"`
+	AvgSellingPrice float64 `json:"avg_selling_price"` // revenue / units
+	MinPrice        float64 `json:"min_price"`
+	MaxPrice        float64 `json:"max_price"`
+	UnitsSold       int     `json:"units_sold"`
+	PriceChanges    int     `json:"price_changes"`
+}
+
+// CategoryPrice for /api/prices/categories
+type CategoryPrice struct {
+	Category        string  `json:"category"`
+	AvgSellingPrice float64 `json:"avg_selling_price"` // revenue / units
+	MinPrice        float64 `json:"min_price"`
+	MaxPrice        float64 `json:"max_price"`
+	UnitsSold       int     `json:"units_sold"`
+}
+
+// PriceChange for /api/prices/changes. Prices are monthly average selling
+// prices; volume is units sold in each of the two months.
+type PriceChange struct {
+	ProductID       string  `json:"product_id"`
+	ProductName     string  `json:"product_name"`
+	PrevMonth       string  `json:"prev_month"`
+	Month           string  `json:"month"`
+	OldPrice        float64 `json:"old_price"`
+	NewPrice        float64 `json:"new_price"`
+	ChangePct       float64 `json:"change_pct"`
+	UnitsBefore     int     `json:"units_before"`
+	UnitsAfter      int     `json:"units_after"`
+	VolumeChangePct float64 `json:"volume_change_pct"`
+	Elasticity      float64 `json:"elasticity"` // volume change % / price change %
+}
+
+// PriceBand for /api/prices/bands
+type PriceBand struct {
+	Band         string   `json:"band"`
+	MinPrice     float64  `json:"min_price"`
+	MaxPrice     *float64 `json:"max_price"` // null for the open-ended top band
+	Transactions int      `json:"transactions"`
+	UnitsSold    int      `json:"units_sold"`
+	Revenue      float64  `json:"revenue"`
+}
+
+// Distribution for /api/distributions, estimated from a quantile sketch
+type Distribution struct {
+	Key       string         `json:"key"` // country, region or month ("all" for the total)
+	Count     int            `json:"count"`
+	Mean      float64        `json:"mean"`
+	Min       float64        `json:"min"`
+	Max       float64        `json:"max"`
+	P50       float64        `json:"p50"`
+	P90       float64        `json:"p90"`
+	P99       float64        `json:"p99"`
+	Histogram []HistogramBin `json:"histogram"`
+}
+
+// HistogramBin is one equal-width bucket of a Distribution histogram
+type HistogramBin struct {
+	Lower float64 `json:"lower"`
+	Upper float64 `json:"upper"`
+	Count int     `json:"count"` // estimated number of samples in [lower, upper)
+}
+
+// Anomaly for /api/anomalies
+type Anomaly struct {
+	Dimension string  `json:"dimension"` // total, country, region or category
+	Key       string  `json:"key"`
+	Period    string  `json:"period"` // YYYY-MM-DD or YYYY-MM
+	Metric    string  `json:"metric"` // revenue or units
+	Expected  float64 `json:"expected"`
+	Actual    float64 `json:"actual"`
+	ZScore    float64 `json:"z_score"`
+	Direction string  `json:"direction"` // spike or drop
+	Severity  string  `json:"severity"`  // low, medium or high
+}
+
+// SeriesPoint is one period of a revenue or units series
+type SeriesPoint struct {
+	Period string  `json:"period"`
+	Value  float64 `json:"value"`
+	SeriesStats
+}
+
+// SeriesStats are derived columns of a time series row, present only when
+// requested with rolling=N or cumulative=true
+type SeriesStats struct {
+	MovingAvg    *float64 `json:"moving_avg,omitempty"` // mean of the last N periods
+	YearToDate   *float64 `json:"ytd,omitempty"`
+	RunningTotal *float64 `json:"running_total,omitempty"`
+}
+
+// ForecastPoint is one forecast period with its confidence interval
+type ForecastPoint struct {
+	Period string  `json:"period"`
+	Value  float64 `json:"value"`
+	Lower  float64 `json:"lower"`
+	Upper  float64 `json:"upper"`
+}
+
+// Backtest holds forecast accuracy on the last periods of history, using a
+// model fitted to the periods before them
+type Backtest struct {
+	Periods int      `json:"periods"`
+	MAE     float64  `json:"mae"`
+	RMSE    float64  `json:"rmse"`
+	MAPE    *float64 `json:"mape"` // percent; null when all actuals are zero
+}
+
+// Forecast for /api/forecast
+type Forecast struct {
+	Dimension string          `json:"dimension"`
+	Key       string          `json:"key"`
+	Metric    string          `json:"metric"`
+	Model     string          `json:"model"` // holt_winters, holt or mean
+	Alpha     float64         `json:"alpha"`
+	Beta      float64         `json:"beta"`
+	Gamma     float64         `json:"gamma"`
+	Level     float64         `json:"level"` // confidence level in percent
+	History   []SeriesPoint   `json:"history"`
+	Forecast  []ForecastPoint `json:"forecast"`
+	Backtest  *Backtest       `json:"backtest"` // null with too little history
+}
+
+// RollupRow for /api/rollup. Key holds one value per hierarchy level,
+// empty where that level is rolled up.
+type RollupRow struct {
+	Key              []string `json:"key"`
+	Grouping         int      `json:"grouping"`               // like SQL GROUPING_ID: a bit per rolled-up level, first level highest
+	Level            string   `json:"level"`                  // levels kept, joined by "+", or "total"
+	ProductName      string   `json:"product_name,omitempty"` // latest name when the product (ID) level is kept
+	TotalRevenue     float64  `json:"total_revenue"`
+	UnitsSold        int      `json:"units_sold"`
+	TransactionCount int      `json:"transaction_count"`
+}
+
+// Breakdown is one member of a breakdown, e.g. a region of a country
+type Breakdown struct {
+	ID               string  `json:"id,omitempty"` // product ID when breaking down by product
+	Name             string  `json:"name"`
+	TotalRevenue     float64 `json:"total_revenue"`
+	UnitsSold        int     `json:"units_sold"`
+	TransactionCount int     `json:"transaction_count"`
+	RevenueShare     float64 `json:"revenue_share"` // of the parent's revenue
+}
+
+// MonthTotal is revenue and units sold in one month
+type MonthTotal struct {
+	Month        string  `json:"month"`
+	TotalRevenue float64 `json:"total_revenue"`
+	UnitsSold    int     `json:"units_sold"`
+}
+
+// Drilldown for /api/countries/:country and /api/regions/:region
+type Drilldown struct {
+	Name             string       `json:"name"`
+	Level            string       `json:"level"` // country or region
+	TotalRevenue     float64      `json:"total_revenue"`
+	UnitsSold        int          `json:"units_sold"`
+	TransactionCount int          `json:"transaction_count"`
+	UniqueCustomers  int          `json:"unique_customers"`
+	AvgOrderValue    float64      `json:"avg_order_value"`
+	Regions          []Breakdown  `json:"regions,omitempty"`   // regions of a country
+	Countries        []Breakdown  `json:"countries,omitempty"` // countries a region appears in
+	ProductCount     int          `json:"product_count"`
+	Products         []Breakdown  `json:"products"` // by revenue, truncated by limit
+	Months           []MonthTotal `json:"months"`   // zero-filled between the first and last sale
+}
+
+// ProductDetail for /api/products/:id
+type ProductDetail struct {
+	InventoryStatus
+	TotalRevenue     float64      `json:"total_revenue"`
+	UnitsSold        int          `json:"units_sold"`
+	TransactionCount int          `json:"transaction_count"`
+	AvgSellingPrice  float64      `json:"avg_selling_price"`
+	MinPrice         float64      `json:"min_price"`
+	MaxPrice         float64      `json:"max_price"`
+	AddedDate        string       `json:"added_date"` // empty when unknown
+	FirstSale        string       `json:"first_sale"`
+	LastSale         string       `json:"last_sale"`
+	Months           []MonthTotal `json:"months"` // zero-filled between the first and last sale
+	TopCountries     []Breakdown  `json:"top_countries"`
+}
+
+// ProductMatch for /api/products/search
+type ProductMatch struct {
+	ProductID    string  `json:"product_id"`
+	ProductName  string  `json:"product_name"`
+	Category     string  `json:"category"`
+	TotalRevenue float64 `json:"total_revenue"`
+	UnitsSold    int     `json:"units_sold"`
+	Match        string  `json:"match"` // id, prefix or substring
+}
+
+// NameCollision is a product name shared by several product IDs
+type NameCollision struct {
+	ProductName string   `json:"product_name"`
+	ProductIDs  []string `json:"product_ids"`
+}
+
+// RenamedProduct is a product ID seen under several names
+type RenamedProduct struct {
+	ProductID   string   `json:"product_id"`
+	CurrentName string   `json:"current_name"` // latest name, used in outputs
+	Names       []string `json:"names"`
+}
+
+// IngestReport for /api/ingest/report
+type IngestReport struct {
+	RowsRead        int              `json:"rows_read"`
+	RowsSkipped     int              `json:"rows_skipped"` // malformed CSV rows and unparseable fields
+	Products        int              `json:"products"`
+	NameCollisions  []NameCollision  `json:"name_collisions"`
+	RenamedProducts []RenamedProduct `json:"renamed_products"`
+	Warnings        []string         `json:"warnings"`
 }
@@ -27,64 +27,103 @@ func NewAggregator(csvPath string) (*Aggregator, error) {
 	return &Aggregator{transactions: txs}, nil
 }
 
+// productLabels returns the latest name and category of each product ID
+func (a *Aggregator) productLabels() map[string]*productLabel {
+	labels := map[string]*productLabel{}
+	for _, t := range a.transactions {
+		l := labels[t.ProductID]
+		if l == nil {
+			l = &productLabel{}
+			labels[t.ProductID] = l
+		}
+		l.rename(t.ProductName, t.Category, t.TransactionDate)
+	}
+	return labels
+}
+
 // RevenueByCountryAndProduct returns a list of total revenue and transaction count
-// grouped by country and product, sorted by highest revenue.
+// grouped by country and product ID, sorted by highest revenue.
 func (a *Aggregator) RevenueByCountryAndProduct() []models.CountryRevenue {
 	tmp := map[struct{ C, P string }]struct {
 		rev float64
 		cnt int
 	}{}
 	for _, t := range a.transactions {
-		key := struct{ C, P string }{t.Country, t.ProductName}
+		key := struct{ C, P string }{t.Country, t.ProductID}
 		v := tmp[key]
 		v.rev += t.TotalPrice
 		v.cnt++
 		tmp[key] = v
 	}
 
+	labels := a.productLabels()
 	out := make([]models.CountryRevenue, 0, len(tmp))
 	for k, v := range tmp {
 		out = append(out, models.CountryRevenue{
 			Country:          k.C,
-			ProductName:      k.P,
+			ProductID:        k.P,
+			ProductName:      labels[k.P].name,
+			Category:         labels[k.P].category,
 			TotalRevenue:     v.rev,
 			TransactionCount: v.cnt,
 		})
 	}
 
 	// Sort by descending revenue
-	sort.Slice(out, func(i, j int) bool {
-		return out[i].TotalRevenue > out[j].TotalRevenue
-	})
+	sort.Slice(out, func(i, j int) bool { return countryRevenueLess(out[i], out[j]) })
 	return out
 }
 
-// TopProducts returns the top N products by total quantity sold
+// countryRevenueLess orders by revenue (desc), then country and product ID
+// so that equal revenues page deterministically
+func countryRevenueLess(a, b models.CountryRevenue) bool {
+	if a.TotalRevenue != b.TotalRevenue {
+		return a.TotalRevenue > b.TotalRevenue
+	}
+	if a.Country != b.Country {
+		return a.Country < b.Country
+	}
+	return a.ProductID < b.ProductID
+}
+
+// TopProducts returns the top N products (by ID) by total quantity sold
 // If two products have the same quantity, they are sorted by name in descending order
 func (a *Aggregator) TopProducts(limit int) []models.ProductFrequency {
-	tmp := map[string]struct{ cnt, stock int }{}
+	tmp := map[string]struct {
+		cnt   int
+		stock stockReading
+	}{}
 	for _, t := range a.transactions {
-		v := tmp[t.ProductName]
+		v := tmp[t.ProductID]
 		v.cnt += t.Quantity
-		v.stock = t.StockQuantity
-		tmp[t.ProductName] = v
+		// keep the stock reported on the latest transaction date
+		if r := (stockReading{qty: t.StockQuantity, at: t.TransactionDate}); r.supersedes(v.stock) {
+			v.stock = r
+		}
+		tmp[t.ProductID] = v
 	}
 
+	labels := a.productLabels()
 	out := make([]models.ProductFrequency, 0, len(tmp))
-	for name, v := range tmp {
+	for id, v := range tmp {
 		out = append(out, models.ProductFrequency{
-			ProductName:   name,
+			ProductID:     id,
+			ProductName:   labels[id].name,
+			Category:      labels[id].category,
 			PurchaseCount: v.cnt,
-			StockQuantity: v.stock,
+			StockQuantity: v.stock.qty,
 		})
 	}
 
-	// Sort by purchase count (desc), then by product name (desc)
+	// Sort by purchase count (desc), then by product name (desc) and ID
 	sort.Slice(out, func(i, j int) bool {
-		if out[i].PurchaseCount == out[j].PurchaseCount {
+		if out[i].PurchaseCount != out[j].PurchaseCount {
+			return out[i].PurchaseCount > out[j].PurchaseCount
+		}
+		if out[i].ProductName != out[j].ProductName {
 			return out[i].ProductName > out[j].ProductName
 		}
-		return out[i].PurchaseCount > out[j].PurchaseCount
+		return out[i].ProductID < out[j].ProductID
 	})
 
 	// Limit the result to the top N products
@@ -94,17 +133,21 @@ func (a *Aggregator) TopProducts(limit int) []models.ProductFrequency {
 	return out
 }
 
-// MonthlySalesVolume returns the quantity sold in each month, sorted chronologically
+// MonthlySalesVolume returns the quantity sold and the number of distinct
+// customers in each month, sorted chronologically
 func (a *Aggregator) MonthlySalesVolume() []models.MonthlySales {
 	tmp := map[string]int{}
+	users := distinctGroups{}
+	exact := Options{ExactDistinct: true}
 	for _, t := range a.transactions {
 		month := t.TransactionDate.Format("2006-01")
 		tmp[month] += t.Quantity
+		users.add(exact, month, t.UserID)
 	}
 
 	out := make([]models.MonthlySales, 0, len(tmp))
 	for m, vol := range tmp {
-		out = append(out, models.MonthlySales{Month: m, SalesVolume: vol})
+		out = append(out, models.MonthlySales{Month: m, SalesVolume: vol, UniqueCustomers: users.count(m)})
 	}
 
 	// Sort by date (ascending)
@@ -122,26 +165,28 @@ func (a *Aggregator) TopRegionsByRevenue(limit int) []models.RegionRevenue {
 		rev  float64
 		sold int
 	}{}
+	users := distinctGroups{}
+	exact := Options{ExactDistinct: true}
 	for _, t := range a.transactions {
 		v := tmp[t.Region]
 		v.rev += t.TotalPrice
 		v.sold += t.Quantity
 		tmp[t.Region] = v
+		users.add(exact, t.Region, t.UserID)
 	}
 
 	out := make([]models.RegionRevenue, 0, len(tmp))
 	for region, v := range tmp {
 		out = append(out, models.RegionRevenue{
-			Region:       region,
-			TotalRevenue: v.rev,
-			ItemsSold:    v.sold,
+			Region:          region,
+			TotalRevenue:    v.rev,
+			ItemsSold:       v.sold,
+			UniqueCustomers: users.count(region),
 		})
 	}
 
 	// Sort by total revenue (desc)
-	sort.Slice(out, func(i, j int) bool {
-		return out[i].TotalRevenue > out[j].TotalRevenue
-	})
+	sort.Slice(out, func(i, j int) bool { return regionRevenueLess(out[i], out[j]) })
 
 	// Limit the result to top N regions
 	if len(out) > limit {
@@ -149,3 +194,11 @@ func (a *Aggregator) TopRegionsByRevenue(limit int) []models.RegionRevenue {
 	}
 	return out
 }
+
+// regionRevenueLess orders by revenue (desc), then region name
+func regionRevenueLess(a, b models.RegionRevenue) bool {
+	if a.TotalRevenue != b.TotalRevenue {
+		return a.TotalRevenue > b.TotalRevenue
+	}
+	return a.Region < b.Region
+}
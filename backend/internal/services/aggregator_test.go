@@ -8,10 +8,11 @@ import (
 	"github.com/GimhaniHM/backend/internal/models"
 )
 
-// create a dummy transaction
+// create a dummy transaction; the product name doubles as its ID
 func makeTransaction(country, product string, qty int, price float64) models.Transaction {
 	return models.Transaction{
 		Country:     country,
+		ProductID:   product,
 		ProductName: product,
 		Quantity:    qty,
 		Price:       price,
@@ -33,8 +34,8 @@ func TestRevenueByCountryAndProduct(t *testing.T) {
 	got := agg.RevenueByCountryAndProduct()
 
 	want := []models.CountryRevenue{
-		{Country: "A", ProductName: "X", TotalRevenue: 50.0, TransactionCount: 2},
-		{Country: "B", ProductName: "Y", TotalRevenue: 5.0, TransactionCount: 1},
+		{Country: "A", ProductID: "X", ProductName: "X", TotalRevenue: 50.0, TransactionCount: 2},
+		{Country: "B", ProductID: "Y", ProductName: "Y", TotalRevenue: 5.0, TransactionCount: 1},
 	}
 
 	// validate the result
@@ -56,8 +57,8 @@ func TestTopProducts(t *testing.T) {
 
 	got := agg.TopProducts(10)
 	want := []models.ProductFrequency{
-		{ProductName: "P2", PurchaseCount: 5, StockQuantity: 0},
-		{ProductName: "P1", PurchaseCount: 5, StockQuantity: 0},
+		{ProductID: "P2", ProductName: "P2", PurchaseCount: 5, StockQuantity: 0},
+		{ProductID: "P1", ProductName: "P1", PurchaseCount: 5, StockQuantity: 0},
 	}
 
 	// validate the result
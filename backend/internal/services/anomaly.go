@@ -0,0 +1,142 @@
+package services
+
+import (
+	"math"
+	"slices"
+	"sort"
+
+	"github.com/GimhaniHM/backend/internal/models"
+)
+
+// Anomaly severities, by how far the z-score exceeds the threshold
+const (
+	SeverityLow    = "low"    // below threshold+1
+	SeverityMedium = "medium" // below threshold+2
+	SeverityHigh   = "high"
+)
+
+// AnomalyQuery selects which series are scanned and how points are scored
+type AnomalyQuery struct {
+	Dimension   string // one of SeriesDimensions, or "" for all
+	Key         string // one member of Dimension, or "" for all
+	Granularity string // GranularityDay or GranularityMonth
+	Metric      string // MetricRevenue or MetricUnits
+	Window      int    // number of preceding periods forming the baseline
+	Threshold   float64
+}
+
+// Anomalies scores each period of the selected series against the mean and
+// standard deviation of the preceding Window periods (a rolling z-score) and
+// returns those at least Threshold deviations away, most extreme first.
+// Periods without a full window of history, or whose window is flat, are not
+// scored. ok is false for an unknown dimension, granularity or metric.
+func (s *SeriesIndex) Anomalies(q AnomalyQuery) (out []models.Anomaly, ok bool) {
+	dims := SeriesDimensions
+	if q.Dimension != "" {
+		if !slices.Contains(SeriesDimensions, q.Dimension) {
+			return nil, false
+		}
+		dims = []string{q.Dimension}
+	}
+	if q.Metric != MetricRevenue && q.Metric != MetricUnits {
+		return nil, false
+	}
+	if q.Granularity != GranularityDay && q.Granularity != GranularityMonth {
+		return nil, false
+	}
+
+	out = make([]models.Anomaly, 0)
+	for _, dim := range dims {
+		for _, key := range s.Keys(dim) {
+			if q.Key != "" && key != q.Key {
+				continue
+			}
+			labels, values, _ := s.Series(dim, key, q.Granularity, q.Metric)
+			for _, o := range rollingOutliers(values, q.Window, q.Threshold) {
+				a := models.Anomaly{
+					Dimension: dim,
+					Key:       key,
+					Period:    labels[o.idx],
+					Metric:    q.Metric,
+					Expected:  o.expected,
+					Actual:    values[o.idx],
+					ZScore:    o.z,
+					Direction: "spike",
+					Severity:  severity(math.Abs(o.z), q.Threshold),
+				}
+				if o.z < 0 {
+					a.Direction = "drop"
+				}
+				out = append(out, a)
+			}
+		}
+	}
+
+	// Sort by deviation (desc), then period, dimension and key
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if math.Abs(a.ZScore) != math.Abs(b.ZScore) {
+			return math.Abs(a.ZScore) > math.Abs(b.ZScore)
+		}
+		if a.Period != b.Period {
+			return a.Period < b.Period
+		}
+		if a.Dimension != b.Dimension {
+			return a.Dimension < b.Dimension
+		}
+		return a.Key < b.Key
+	})
+	return out, true
+}
+
+// a scored point of a series
+type outlier struct {
+	idx         int
+	expected, z float64
+}
+
+// rollingOutliers returns the points at least threshold standard deviations
+// from the mean of the window points before them. The window's mean and sum
+// of squared deviations are updated in place (Welford's method), so each
+// series is scanned once without the cancellation of raw sums of squares at
+// revenue-sized values.
+func rollingOutliers(values []float64, window int, threshold float64) []outlier {
+	var out []outlier
+	if window < 2 || len(values) <= window {
+		return out
+	}
+	var mean, m2 float64
+	for i, v := range values[:window] {
+		d := v - mean
+		mean += d / float64(i+1)
+		m2 += d * (v - mean)
+	}
+	n := float64(window)
+	for i := window; i < len(values); i++ {
+		v := values[i]
+		variance := math.Max(m2/n, 0)
+		if variance > 1e-24*(1+mean*mean) { // not flat, up to rounding
+			if z := (v - mean) / math.Sqrt(variance); math.Abs(z) >= threshold {
+				out = append(out, outlier{idx: i, expected: mean, z: z})
+			}
+		}
+		// Slide the window: v replaces the oldest point
+		old := values[i-window]
+		next := mean + (v-old)/n
+		m2 = math.Max(m2+(v-old)*(v-next+old-mean), 0)
+		mean = next
+	}
+	return out
+}
+
+// severity grades an absolute z-score relative to the threshold
+func severity(absZ, threshold float64) string {
+	switch {
+	case absZ < threshold+1:
+		return SeverityLow
+	case absZ < threshold+2:
+		return SeverityMedium
+	default:
+		return SeverityHigh
+	}
+}
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/GimhaniHM/backend/internal/models"
+)
+
+// TestAnomalies checks rolling z-scores flag a daily spike and a drop to
+// zero on a day without sales
+func TestAnomalies(t *testing.T) {
+	start, _ := time.Parse("2006-01-02", "2024-01-01")
+	series := make(seriesPart)
+	for d := 0; d < 120; d++ {
+		if d == 80 {
+			continue // a gap is a zero-revenue day
+		}
+		rev := 100.0 + float64(d%3) // 100, 101, 102, ...
+		if d == 40 {
+			rev = 400
+		}
+		series.add(models.Transaction{
+			Country: "USA", Region: "West", Category: "Toys",
+			TransactionDate: start.AddDate(0, 0, d), Quantity: 1, TotalPrice: rev,
+		})
+	}
+	idx := &SeriesIndex{days: series}
+
+	labels, values, ok := idx.Series("country", "USA", GranularityDay, MetricRevenue)
+	if !ok || len(values) != 120 || labels[80] != "2024-03-21" || values[80] != 0 {
+		t.Fatalf("Series(day) = %d values, ok %v; want 120 zero-filled days", len(values), ok)
+	}
+	if _, _, ok := idx.Series("country", "UK", GranularityDay, MetricRevenue); ok {
+		t.Errorf("Series for unknown key returned ok")
+	}
+
+	got, ok := idx.Anomalies(AnomalyQuery{Dimension: "country", Granularity: GranularityDay, Metric: MetricRevenue, Window: 28, Threshold: 3})
+	if !ok || len(got) != 2 {
+		t.Fatalf("Anomalies(day) = %+v; want the spike and the gap", got)
+	}
+	if a := got[0]; a.Period != "2024-02-10" || a.Direction != "spike" || a.Actual != 400 || a.Severity != SeverityHigh {
+		t.Errorf("first anomaly = %+v; want high spike on 2024-02-10", a)
+	}
+	if a := got[1]; a.Period != "2024-03-21" || a.Direction != "drop" || a.Actual != 0 {
+		t.Errorf("second anomaly = %+v; want drop on 2024-02-15", a)
+	}
+
+	if _, ok := idx.Anomalies(AnomalyQuery{Dimension: "city", Granularity: GranularityDay, Metric: MetricRevenue, Window: 28, Threshold: 3}); ok {
+		t.Errorf("unknown dimension accepted")
+	}
+}
+
+// TestRollingOutliersLargeValues checks the rolling variance stays exact
+// when small swings ride on revenue-sized values
+func TestRollingOutliersLargeValues(t *testing.T) {
+	values := make([]float64, 60)
+	for i := range values {
+		values[i] = 1e9 + float64(i%3)
+	}
+	values[45] = 1e9 + 50
+	got := rollingOutliers(values, 28, 3)
+	if len(got) != 1 || got[0].idx != 45 || math.IsNaN(got[0].z) {
+		t.Errorf("rollingOutliers = %+v; want only index 45", got)
+	}
+}
@@ -0,0 +1,501 @@
+package services
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/GimhaniHM/backend/internal/models"
+)
+
+// basketBuckets is the number of spill files per worker. Rows are spread
+// over them by user, so each file holds complete baskets and mining loads
+// one file at a time.
+const basketBuckets = 64
+
+// one purchased line, with the user hashed and the product interned to keep
+// rows small; a row takes basketRowSize bytes in a spill file
+type basketRow struct {
+	user uint64
+	day  int32
+	item uint32
+}
+
+const basketRowSize = 16
+
+// bucket returns the spill file a user's rows go to
+func (r basketRow) bucket() int {
+	return int(r.user >> 58) // top 6 bits: 64 buckets
+}
+
+// basketPart collects one worker's purchase lines. Records are sharded by
+// user, so every basket (same user, same date) is complete in one worker.
+// The CSV need not be ordered by user or date, so a basket is only known to
+// be complete at the end of the pass. Lines are buffered up to
+// Options.BasketBufferRows and then spilled to temporary files, one per
+// bucket of users, so memory stays bounded however many rows are read.
+type basketPart struct {
+	items   map[string]uint32
+	itemIDs []string
+	names   []datedName // latest name per interned item
+	rows    []basketRow
+	limit   int    // rows buffered before a spill
+	dir     string // spill directory, "" until the first spill
+	err     error  // first spill error, reported by buckets
+}
+
+// a product name with the day it was last seen
+type datedName struct {
+	name string
+	day  int32
+}
+
+// newer reports whether n should replace o as the latest name
+func (n datedName) newer(o datedName) bool {
+	if n.day != o.day {
+		return n.day > o.day
+	}
+	return n.name > o.name
+}
+
+// creates an empty basketPart
+func newBasketPart(opts Options) *basketPart {
+	return &basketPart{
+		items: make(map[string]uint32),
+		limit: opts.BasketBufferRows,
+	}
+}
+
+// add records a purchase line for basket mining
+func (p *basketPart) add(t models.Transaction) {
+	if t.UserID == "" || t.ProductID == "" {
+		return
+	}
+	day := int32(t.TransactionDate.Unix() / 86400)
+	name := datedName{name: t.ProductName, day: day}
+	it, ok := p.items[t.ProductID]
+	if !ok {
+		it = uint32(len(p.itemIDs))
+		p.items[t.ProductID] = it
+		p.itemIDs = append(p.itemIDs, t.ProductID)
+		p.names = append(p.names, name)
+	} else if name.newer(p.names[it]) {
+		p.names[it] = name
+	}
+	p.rows = append(p.rows, basketRow{user: hashKey(t.UserID), day: day, item: it})
+	if len(p.rows) >= p.limit {
+		p.spill()
+	}
+}
+
+// spill appends the buffered rows to their bucket files and empties the
+// buffer. After an error, rows are dropped and the error is kept.
+func (p *basketPart) spill() {
+	defer func() { p.rows = p.rows[:0] }()
+	if p.err != nil {
+		return
+	}
+	if p.dir == "" {
+		dir, err := os.MkdirTemp("", "baskets-")
+		if err != nil {
+			p.err = fmt.Errorf("spill baskets: %w", err)
+			return
+		}
+		p.dir = dir
+	}
+
+	// Group rows by bucket so each file is opened once per spill
+	sort.Slice(p.rows, func(i, j int) bool { return p.rows[i].bucket() < p.rows[j].bucket() })
+	for start := 0; start < len(p.rows); {
+		b := p.rows[start].bucket()
+		end := start
+		for end < len(p.rows) && p.rows[end].bucket() == b {
+			end++
+		}
+		if err := appendRows(p.bucketPath(b), p.rows[start:end]); err != nil {
+			p.err = fmt.Errorf("spill baskets: %w", err)
+			return
+		}
+		start = end
+	}
+}
+
+// bucketPath returns the spill file of bucket b
+func (p *basketPart) bucketPath(b int) string {
+	return filepath.Join(p.dir, fmt.Sprintf("%02d", b))
+}
+
+// appendRows appends rows to the spill file at path
+func appendRows(path string, rows []basketRow) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	var buf [basketRowSize]byte
+	for _, r := range rows {
+		binary.LittleEndian.PutUint64(buf[0:], r.user)
+		binary.LittleEndian.PutUint32(buf[8:], uint32(r.day))
+		binary.LittleEndian.PutUint32(buf[12:], r.item)
+		w.Write(buf[:])
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// buckets calls fn with the rows of each bucket in turn, then removes the
+// spill files. Without a spill, every buffered row is one bucket.
+func (p *basketPart) buckets(fn func(rows []basketRow)) error {
+	defer p.cleanup()
+	if p.dir == "" {
+		fn(p.rows)
+		p.rows = nil
+		return p.err
+	}
+	p.spill()
+	p.rows = nil
+	if p.err != nil {
+		return p.err
+	}
+
+	var rows []basketRow
+	for b := 0; b < basketBuckets; b++ {
+		data, err := os.ReadFile(p.bucketPath(b))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("read spilled baskets: %w", err)
+		}
+		rows = rows[:0]
+		for i := 0; i+basketRowSize <= len(data); i += basketRowSize {
+			rows = append(rows, basketRow{
+				user: binary.LittleEndian.Uint64(data[i:]),
+				day:  int32(binary.LittleEndian.Uint32(data[i+8:])),
+				item: binary.LittleEndian.Uint32(data[i+12:]),
+			})
+		}
+		fn(rows)
+	}
+	return nil
+}
+
+// cleanup removes the spill files, if any
+func (p *basketPart) cleanup() {
+	if p.dir != "" {
+		os.RemoveAll(p.dir)
+		p.dir = ""
+	}
+}
+
+// basketCounts holds item and pair basket counts keyed by product ID
+type basketCounts struct {
+	baskets int
+	items   map[string]int
+	pairs   map[[2]string]int
+	names   map[string]datedName
+	pruned  bool
+}
+
+// basketMiner groups every worker's rows into baskets and counts how many
+// baskets contain each product and each product pair. All workers share one
+// pair table, capped at Options.BasketMaxPairs by dropping the rarest pairs,
+// so pair counts become lower bounds once pruned.
+type basketMiner struct {
+	opts    Options
+	items   map[string]uint32
+	itemIDs []string
+	names   []datedName
+	itemCnt []int
+	pairCnt map[uint64]int
+	baskets int
+	pruned  bool
+}
+
+// creates an empty basketMiner
+func newBasketMiner(opts Options) *basketMiner {
+	return &basketMiner{
+		opts:    opts,
+		items:   make(map[string]uint32),
+		pairCnt: make(map[uint64]int),
+	}
+}
+
+// add mines one worker's baskets, one bucket at a time
+func (m *basketMiner) add(p *basketPart) error {
+	// Map the worker's interned items onto the miner's
+	local := make([]uint32, len(p.itemIDs))
+	for i, id := range p.itemIDs {
+		it, ok := m.items[id]
+		if !ok {
+			it = uint32(len(m.itemIDs))
+			m.items[id] = it
+			m.itemIDs = append(m.itemIDs, id)
+			m.names = append(m.names, p.names[i])
+			m.itemCnt = append(m.itemCnt, 0)
+		} else if p.names[i].newer(m.names[it]) {
+			m.names[it] = p.names[i]
+		}
+		local[i] = it
+	}
+
+	basket := make([]uint32, 0, 16)
+	return p.buckets(func(rows []basketRow) {
+		for i := range rows {
+			rows[i].item = local[rows[i].item]
+		}
+		sort.Slice(rows, func(i, j int) bool {
+			a, b := rows[i], rows[j]
+			if a.user != b.user {
+				return a.user < b.user
+			}
+			if a.day != b.day {
+				return a.day < b.day
+			}
+			return a.item < b.item
+		})
+		for i, r := range rows {
+			if i > 0 && (r.user != rows[i-1].user || r.day != rows[i-1].day) {
+				m.count(basket)
+				basket = basket[:0]
+			}
+			// rows are sorted by item within a basket, so skip repeats
+			if n := len(basket); n == 0 || basket[n-1] != r.item {
+				basket = append(basket, r.item)
+			}
+		}
+		m.count(basket)
+		basket = basket[:0]
+	})
+}
+
+// count records one basket of distinct items in ascending order
+func (m *basketMiner) count(basket []uint32) {
+	if len(basket) == 0 {
+		return
+	}
+	m.baskets++
+	for _, it := range basket {
+		m.itemCnt[it]++
+	}
+	if len(basket) > m.opts.BasketMaxItems {
+		return
+	}
+	for i := 0; i < len(basket); i++ {
+		for j := i + 1; j < len(basket); j++ {
+			m.pairCnt[uint64(basket[i])<<32|uint64(basket[j])]++
+		}
+	}
+	if len(m.pairCnt) > m.opts.BasketMaxPairs {
+		prunePairs(m.pairCnt, m.opts.BasketMaxPairs/2)
+		m.pruned = true
+	}
+}
+
+// results translates interned IDs back to product IDs
+func (m *basketMiner) results() basketCounts {
+	out := basketCounts{
+		baskets: m.baskets,
+		items:   make(map[string]int, len(m.itemIDs)),
+		pairs:   make(map[[2]string]int, len(m.pairCnt)),
+		names:   make(map[string]datedName, len(m.itemIDs)),
+		pruned:  m.pruned,
+	}
+	for it, id := range m.itemIDs {
+		out.items[id] = m.itemCnt[it]
+		out.names[id] = m.names[it]
+	}
+	for k, n := range m.pairCnt {
+		out.pairs[orderedPair(m.itemIDs[k>>32], m.itemIDs[uint32(k)])] = n
+	}
+	return out
+}
+
+// orderedPair returns the two IDs in ascending order
+func orderedPair(a, b string) [2]string {
+	if a > b {
+		a, b = b, a
+	}
+	return [2]string{a, b}
+}
+
+// prunePairs drops the least frequent pairs until about keep remain. The
+// cut-off count comes from a histogram of counts rather than a sort, so this
+// is linear in the table size. Pairs at the cut-off are kept or dropped by
+// a hash of their key, so no product is favoured over another.
+func prunePairs(m map[uint64]int, keep int) {
+	if len(m) <= keep {
+		return
+	}
+	hist := make(map[int]int)
+	for _, n := range m {
+		hist[n]++
+	}
+	counts := make([]int, 0, len(hist))
+	for n := range hist {
+		counts = append(counts, n)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(counts)))
+	cut, kept := 0, 0
+	for _, n := range counts {
+		if kept+hist[n] > keep {
+			cut = n
+			break
+		}
+		kept += hist[n]
+	}
+
+	// Share of the pairs at the cut-off to keep, out of 2^32
+	share := (uint64(keep-kept) << 32) / uint64(hist[cut])
+	for k, n := range m {
+		if n < cut || (n == cut && mix64(k)>>32 >= share) {
+			delete(m, k)
+		}
+	}
+}
+
+// mix64 scrambles the bits of a key (the splitmix64 finalizer)
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// BasketIndex answers product association queries over mined baskets
+type BasketIndex struct {
+	Baskets     int  // number of baskets mined
+	Approximate bool // true if rare pairs were pruned to bound memory
+
+	items   map[string]int
+	names   map[string]string
+	pairs   []models.ProductPair
+	related map[string][]int // product ID -> indexes into pairs
+}
+
+// newBasketIndex builds association metrics from mined counts, keeping
+// pairs seen in at least minCount baskets
+func newBasketIndex(c basketCounts, minCount int) *BasketIndex {
+	idx := &BasketIndex{
+		Baskets:     c.baskets,
+		Approximate: c.pruned,
+		items:       c.items,
+		names:       make(map[string]string, len(c.names)),
+		pairs:       make([]models.ProductPair, 0),
+		related:     make(map[string][]int),
+	}
+	for id, n := range c.names {
+		idx.names[id] = n.name
+	}
+	n := float64(c.baskets)
+	for k, cnt := range c.pairs {
+		if cnt < minCount {
+			continue
+		}
+		ca, cb := float64(c.items[k[0]]), float64(c.items[k[1]])
+		idx.pairs = append(idx.pairs, models.ProductPair{
+			ProductA:     k[0],
+			ProductNameA: idx.names[k[0]],
+			ProductB:     k[1],
+			ProductNameB: idx.names[k[1]],
+			Baskets:      cnt,
+			Support:      float64(cnt) / n,
+			ConfidenceAB: float64(cnt) / ca,
+			ConfidenceBA: float64(cnt) / cb,
+			Lift:         float64(cnt) * n / (ca * cb),
+		})
+	}
+
+	// Sort pairs by basket count (desc), then by lift (desc), then by IDs
+	sort.Slice(idx.pairs, func(i, j int) bool {
+		a, b := idx.pairs[i], idx.pairs[j]
+		if a.Baskets != b.Baskets {
+			return a.Baskets > b.Baskets
+		}
+		if a.Lift != b.Lift {
+			return a.Lift > b.Lift
+		}
+		if a.ProductA != b.ProductA {
+			return a.ProductA < b.ProductA
+		}
+		return a.ProductB < b.ProductB
+	})
+	for i, pr := range idx.pairs {
+		idx.related[pr.ProductA] = append(idx.related[pr.ProductA], i)
+		idx.related[pr.ProductB] = append(idx.related[pr.ProductB], i)
+	}
+	return idx
+}
+
+// TopPairs returns the most frequently co-purchased pairs
+func (b *BasketIndex) TopPairs(limit int) []models.ProductPair {
+	if b == nil {
+		return []models.ProductPair{}
+	}
+	if len(b.pairs) < limit {
+		limit = len(b.pairs)
+	}
+	return b.pairs[:limit]
+}
+
+// BasketCount returns how many baskets contain the product
+func (b *BasketIndex) BasketCount(productID string) int {
+	if b == nil {
+		return 0
+	}
+	return b.items[productID]
+}
+
+// Related returns association rules "productID -> other" sorted by lift
+// (desc), then confidence (desc). ok is false for unknown products.
+func (b *BasketIndex) Related(productID string, limit int) (rules []models.ProductAssociation, ok bool) {
+	if b == nil {
+		return nil, false
+	}
+	if _, ok := b.items[productID]; !ok {
+		return nil, false
+	}
+
+	rules = make([]models.ProductAssociation, 0, len(b.related[productID]))
+	for _, i := range b.related[productID] {
+		pr := b.pairs[i]
+		r := models.ProductAssociation{Baskets: pr.Baskets, Support: pr.Support, Lift: pr.Lift}
+		if pr.ProductA == productID {
+			r.ProductID, r.ProductName, r.Confidence = pr.ProductB, pr.ProductNameB, pr.ConfidenceAB
+		} else {
+			r.ProductID, r.ProductName, r.Confidence = pr.ProductA, pr.ProductNameA, pr.ConfidenceBA
+		}
+		rules = append(rules, r)
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		if rules[i].Lift != rules[j].Lift {
+			return rules[i].Lift > rules[j].Lift
+		}
+		if rules[i].Confidence != rules[j].Confidence {
+			return rules[i].Confidence > rules[j].Confidence
+		}
+		return rules[i].ProductID < rules[j].ProductID
+	})
+	if len(rules) > limit {
+		rules = rules[:limit]
+	}
+	return rules, true
+}
+
+// ProductName returns the latest name seen for a product ID
+func (b *BasketIndex) ProductName(productID string) string {
+	if b == nil {
+		return ""
+	}
+	return b.names[productID]
+}
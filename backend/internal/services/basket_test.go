@@ -0,0 +1,60 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/GimhaniHM/backend/internal/models"
+)
+
+// TestPrunePairs checks pruning keeps the frequent pairs and about the
+// requested number overall, even when most pairs share the cut-off count
+func TestPrunePairs(t *testing.T) {
+	m := map[uint64]int{1<<32 | 2: 5, 1<<32 | 3: 4}
+	for i := uint64(0); i < 1000; i++ {
+		m[(i+10)<<32|(i+2000)] = 1
+	}
+	prunePairs(m, 202)
+
+	if m[1<<32|2] != 5 || m[1<<32|3] != 4 {
+		t.Errorf("prunePairs dropped a frequent pair")
+	}
+	if n := len(m); n < 150 || n > 250 {
+		t.Errorf("prunePairs kept %d pairs; want about 202", n)
+	}
+}
+
+// TestBasketPartSpills checks spilled rows come back bucket by bucket, with
+// each user's rows in one bucket, and that the spill files are removed
+func TestBasketPartSpills(t *testing.T) {
+	opts := DefaultOptions()
+	opts.BasketBufferRows = 3
+	p := newBasketPart(opts)
+	day := time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 20; i++ {
+		p.add(models.Transaction{UserID: fmt.Sprintf("U%d", i%5), ProductID: "P1", TransactionDate: day})
+	}
+	dir := p.dir
+	if dir == "" {
+		t.Fatal("no spill after 20 rows with a buffer of 3")
+	}
+
+	rows, users := 0, make(map[uint64]int)
+	err := p.buckets(func(b []basketRow) {
+		rows += len(b)
+		for _, r := range b {
+			if prev, ok := users[r.user]; ok && prev != r.bucket() {
+				t.Errorf("user %x in buckets %d and %d", r.user, prev, r.bucket())
+			}
+			users[r.user] = r.bucket()
+		}
+	})
+	if err != nil || rows != 20 || len(users) != 5 {
+		t.Errorf("buckets = %d rows, %d users, err %v; want 20 rows of 5 users", rows, len(users), err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("spill directory %s still exists", dir)
+	}
+}
@@ -0,0 +1,176 @@
+package services
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/GimhaniHM/backend/internal/models"
+)
+
+// Product search match kinds, in ranking order
+const (
+	MatchID        = "id"
+	MatchPrefix    = "prefix"
+	MatchSubstring = "substring"
+)
+
+// ProductIndex looks up products by ID and searches their names and IDs
+type ProductIndex struct {
+	products  productPart
+	inventory map[string]models.InventoryStatus
+
+	ids    []string       // product IDs, ordered by revenue (desc) then ID
+	byID   map[string]int // lowercase ID -> position in ids
+	lowers []string       // lowercase "name id" per entry of ids, for substring scans
+	terms  []term         // sorted lowercase IDs, names and name words, for prefix lookups
+}
+
+// a searchable token pointing at a position in ids
+type term struct {
+	text string
+	pos  int
+}
+
+// newProductIndex indexes the merged products and their inventory status
+func newProductIndex(products productPart, inventory []models.InventoryStatus) *ProductIndex {
+	x := &ProductIndex{
+		products:  products,
+		inventory: make(map[string]models.InventoryStatus, len(inventory)),
+		ids:       make([]string, 0, len(products)),
+	}
+	for _, st := range inventory {
+		x.inventory[st.ProductID] = st
+	}
+	for id := range products {
+		x.ids = append(x.ids, id)
+	}
+	sort.Slice(x.ids, func(i, j int) bool {
+		a, b := products[x.ids[i]], products[x.ids[j]]
+		if a.revenue != b.revenue {
+			return a.revenue > b.revenue
+		}
+		return x.ids[i] < x.ids[j]
+	})
+
+	x.byID = make(map[string]int, len(x.ids))
+	x.lowers = make([]string, len(x.ids))
+	for pos, id := range x.ids {
+		lowerID, name := strings.ToLower(id), strings.ToLower(products[id].name)
+		x.byID[lowerID] = pos
+		x.lowers[pos] = name + " " + lowerID
+		x.terms = append(x.terms, term{lowerID, pos}, term{name, pos})
+		// later words of the name; the first is covered by the full name
+		for i, w := range strings.Fields(name) {
+			if i > 0 {
+				x.terms = append(x.terms, term{w, pos})
+			}
+		}
+	}
+	sort.Slice(x.terms, func(i, j int) bool {
+		if x.terms[i].text != x.terms[j].text {
+			return x.terms[i].text < x.terms[j].text
+		}
+		return x.terms[i].pos < x.terms[j].pos
+	})
+	return x
+}
+
+// Detail returns a product's totals, stock, monthly trend and its top
+// countries by revenue (at most topCountries)
+func (x *ProductIndex) Detail(id string, topCountries int) (models.ProductDetail, bool) {
+	acc, ok := x.products[id]
+	if !ok {
+		return models.ProductDetail{}, false
+	}
+	d := models.ProductDetail{
+		InventoryStatus:  x.inventory[id],
+		TotalRevenue:     acc.revenue,
+		UnitsSold:        acc.units,
+		TransactionCount: acc.orders,
+		MinPrice:         acc.minPrice,
+		MaxPrice:         acc.maxPrice,
+		FirstSale:        acc.firstSale.Format("2006-01-02"),
+		LastSale:         acc.lastSale.Format("2006-01-02"),
+		Months:           make([]models.MonthTotal, 0, len(acc.months)),
+	}
+	if acc.units > 0 {
+		d.AvgSellingPrice = acc.revenue / float64(acc.units)
+	}
+	if !acc.added.IsZero() {
+		d.AddedDate = acc.added.Format("2006-01-02")
+	}
+
+	first, last := monthIndex(acc.firstSale), monthIndex(acc.lastSale)
+	for mi := first; mi <= last; mi++ {
+		m := models.MonthTotal{Month: monthLabel(mi)}
+		if pm := acc.months[mi]; pm != nil {
+			m.TotalRevenue, m.UnitsSold = pm.rev, pm.units
+		}
+		d.Months = append(d.Months, m)
+	}
+
+	d.TopCountries = drillChildren(acc.countries, acc.revenue)
+	if len(d.TopCountries) > topCountries {
+		d.TopCountries = d.TopCountries[:topCountries]
+	}
+	return d, true
+}
+
+// Search finds products whose ID equals q, whose name, ID or a word of the
+// name starts with q, or whose name or ID contains q, ignoring case. Results
+// are ranked by match kind, then revenue (desc); at most limit are returned
+// along with the number of matches.
+func (x *ProductIndex) Search(q string, limit int) (int, []models.ProductMatch) {
+	q = strings.ToLower(strings.TrimSpace(q))
+	if q == "" {
+		return 0, []models.ProductMatch{}
+	}
+
+	kind := make(map[int]string)
+	if pos, ok := x.byID[q]; ok {
+		kind[pos] = MatchID
+	}
+	start := sort.Search(len(x.terms), func(i int) bool { return x.terms[i].text >= q })
+	for i := start; i < len(x.terms) && strings.HasPrefix(x.terms[i].text, q); i++ {
+		if _, seen := kind[x.terms[i].pos]; !seen {
+			kind[x.terms[i].pos] = MatchPrefix
+		}
+	}
+	for pos, s := range x.lowers {
+		if _, seen := kind[pos]; !seen && strings.Contains(s, q) {
+			kind[pos] = MatchSubstring
+		}
+	}
+
+	rank := map[string]int{MatchID: 0, MatchPrefix: 1, MatchSubstring: 2}
+	hits := make([]int, 0, len(kind))
+	for pos := range kind {
+		hits = append(hits, pos)
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		a, b := hits[i], hits[j]
+		if rank[kind[a]] != rank[kind[b]] {
+			return rank[kind[a]] < rank[kind[b]]
+		}
+		return a < b // ids are already ordered by revenue
+	})
+
+	total := len(hits)
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+	out := make([]models.ProductMatch, 0, len(hits))
+	for _, pos := range hits {
+		id := x.ids[pos]
+		acc := x.products[id]
+		out = append(out, models.ProductMatch{
+			ProductID:    id,
+			ProductName:  acc.name,
+			Category:     acc.category,
+			TotalRevenue: acc.revenue,
+			UnitsSold:    acc.units,
+			Match:        kind[pos],
+		})
+	}
+	return total, out
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GimhaniHM/backend/internal/models"
+)
+
+// TestProductIndex checks product detail aggregation and that search ranks
+// ID, prefix and substring matches
+func TestProductIndex(t *testing.T) {
+	parse := func(s string) time.Time {
+		d, _ := time.Parse("2006-01-02", s)
+		return d
+	}
+	tx := func(id, name, country, date string, rev float64) models.Transaction {
+		return models.Transaction{
+			ProductID: id, ProductName: name, Category: "Toys", Country: country,
+			TransactionDate: parse(date), Price: rev, Quantity: 1, TotalPrice: rev, StockQuantity: 7,
+		}
+	}
+	products := make(productPart)
+	for _, r := range []models.Transaction{
+		tx("P1", "Red Ball", "USA", "2024-01-05", 10),
+		tx("P1", "Red Ball", "UK", "2024-03-05", 30),
+		tx("P2", "Blue Ball", "USA", "2024-02-05", 50),
+		tx("BALL9", "Kite", "USA", "2024-02-05", 5),
+		tx("P3", "Football", "USA", "2024-02-05", 1),
+	} {
+		products.add(r)
+	}
+	inventory, _ := products.inventory(DefaultOptions())
+	idx := newProductIndex(products, inventory)
+
+	d, ok := idx.Detail("P1", 1)
+	if !ok || d.ProductName != "Red Ball" || d.TotalRevenue != 40 || d.TransactionCount != 2 || d.CurrentStock != 7 || d.LastSale != "2024-03-05" {
+		t.Fatalf("Detail(P1) = %+v, %v", d, ok)
+	}
+	if len(d.Months) != 3 || d.Months[1].TotalRevenue != 0 || len(d.TopCountries) != 1 || d.TopCountries[0].Name != "UK" || d.TopCountries[0].RevenueShare != 0.75 {
+		t.Errorf("Detail(P1) months %+v, countries %+v", d.Months, d.TopCountries)
+	}
+	if _, ok := idx.Detail("P9", 1); ok {
+		t.Errorf("unknown product found")
+	}
+
+	total, got := idx.Search("BALL", 10)
+	want := []struct{ id, match string }{
+		{"P2", MatchPrefix}, // word prefix, more revenue
+		{"P1", MatchPrefix},
+		{"BALL9", MatchPrefix}, // ID prefix
+		{"P3", MatchSubstring},
+	}
+	if total != len(want) {
+		t.Fatalf("Search(BALL) = %d matches %+v; want %d", total, got, len(want))
+	}
+	for i, w := range want {
+		if got[i].ProductID != w.id || got[i].Match != w.match {
+			t.Errorf("match %d = %+v; want %s by %s", i, got[i], w.id, w.match)
+		}
+	}
+	if _, got := idx.Search("p3", 10); len(got) != 1 || got[0].Match != MatchID {
+		t.Errorf("Search(p3) = %+v; want one ID match", got)
+	}
+}
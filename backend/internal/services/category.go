@@ -0,0 +1,204 @@
+package services
+
+import (
+	"sort"
+
+	"github.com/GimhaniHM/backend/internal/models"
+)
+
+// running totals for one category bucket
+type categoryTotals struct {
+	rev   float64
+	units int
+	cnt   int
+}
+
+// unit price range seen in a category
+type priceRange struct {
+	min, max float64
+}
+
+// categoryPart holds one worker's category aggregates
+type categoryPart struct {
+	bandEdges   []float64 // lower bounds of the price bands
+	productOpts Options   // how distinct products are counted
+
+	totals   map[string]categoryTotals
+	products distinctGroups // distinct product IDs per category
+	monthly  map[struct{ C, M string }]categoryTotals
+	region   map[struct{ C, R string }]categoryTotals
+	prices   map[string]priceRange
+	bands    map[string][]categoryTotals // per category, one entry per band
+}
+
+// creates an empty categoryPart with all maps initialised. Distinct products
+// are estimated with HyperLogLog when opts.TopKCapacity is set, so no exact
+// per-product set is kept.
+func newCategoryPart(opts Options) *categoryPart {
+	productOpts := opts
+	productOpts.ExactDistinct = opts.TopKCapacity == 0
+	return &categoryPart{
+		bandEdges:   opts.PriceBands,
+		productOpts: productOpts,
+		totals:      make(map[string]categoryTotals),
+		products:    make(distinctGroups),
+		monthly:     make(map[struct{ C, M string }]categoryTotals),
+		region:      make(map[struct{ C, R string }]categoryTotals),
+		prices:      make(map[string]priceRange),
+		bands:       make(map[string][]categoryTotals),
+	}
+}
+
+// add folds a single transaction into the partial aggregates
+func (p *categoryPart) add(t models.Transaction, month string) {
+	ct := p.totals[t.Category]
+	ct.rev += t.TotalPrice
+	ct.units += t.Quantity
+	ct.cnt++
+	p.totals[t.Category] = ct
+
+	p.products.add(p.productOpts, t.Category, t.ProductID)
+
+	mk := struct{ C, M string }{t.Category, month}
+	mv := p.monthly[mk]
+	mv.rev += t.TotalPrice
+	mv.units += t.Quantity
+	p.monthly[mk] = mv
+
+	rk := struct{ C, R string }{t.Category, t.Region}
+	rv := p.region[rk]
+	rv.rev += t.TotalPrice
+	rv.units += t.Quantity
+	p.region[rk] = rv
+
+	pr, ok := p.prices[t.Category]
+	if !ok {
+		pr = priceRange{min: t.Price, max: t.Price}
+	}
+	pr.min = min(pr.min, t.Price)
+	pr.max = max(pr.max, t.Price)
+	p.prices[t.Category] = pr
+
+	bands := p.bands[t.Category]
+	if bands == nil {
+		bands = make([]categoryTotals, len(p.bandEdges))
+		p.bands[t.Category] = bands
+	}
+	if b := priceBand(p.bandEdges, t.Price); b >= 0 {
+		bands[b].rev += t.TotalPrice
+		bands[b].units += t.Quantity
+		bands[b].cnt++
+	}
+}
+
+// priceBand returns the index of the band containing price, or -1 if the
+// price is below the first band
+func priceBand(edges []float64, price float64) int {
+	return sort.Search(len(edges), func(i int) bool { return edges[i] > price }) - 1
+}
+
+// merge combines another worker's partial into p
+func (p *categoryPart) merge(o *categoryPart) {
+	for k, v := range o.totals {
+		ct := p.totals[k]
+		ct.rev += v.rev
+		ct.units += v.units
+		ct.cnt += v.cnt
+		p.totals[k] = ct
+	}
+	p.products.merge(o.products)
+	for k, v := range o.monthly {
+		mv := p.monthly[k]
+		mv.rev += v.rev
+		mv.units += v.units
+		p.monthly[k] = mv
+	}
+	for k, v := range o.region {
+		rv := p.region[k]
+		rv.rev += v.rev
+		rv.units += v.units
+		p.region[k] = rv
+	}
+	for k, v := range o.prices {
+		pr, ok := p.prices[k]
+		if !ok {
+			p.prices[k] = v
+			continue
+		}
+		pr.min = min(pr.min, v.min)
+		pr.max = max(pr.max, v.max)
+		p.prices[k] = pr
+	}
+	for k, v := range o.bands {
+		bands := p.bands[k]
+		if bands == nil {
+			p.bands[k] = v
+			continue
+		}
+		for i := range v {
+			bands[i].rev += v[i].rev
+			bands[i].units += v[i].units
+			bands[i].cnt += v[i].cnt
+		}
+	}
+}
+
+// results converts the merged maps into sorted output slices
+func (p *categoryPart) results() ([]models.CategoryRevenue, []models.CategoryMonthly, []models.CategoryRegion) {
+	var grand float64
+	for _, v := range p.totals {
+		grand += v.rev
+	}
+
+	// Sort categories by revenue (desc), then by name (asc)
+	cats := make([]models.CategoryRevenue, 0, len(p.totals))
+	for k, v := range p.totals {
+		share := 0.0
+		if grand > 0 {
+			share = v.rev / grand
+		}
+		cats = append(cats, models.CategoryRevenue{
+			Category:         k,
+			TotalRevenue:     v.rev,
+			UnitsSold:        v.units,
+			TransactionCount: v.cnt,
+			DistinctProducts: p.products.count(k),
+			RevenueShare:     share,
+		})
+	}
+	sort.Slice(cats, func(i, j int) bool {
+		if cats[i].TotalRevenue == cats[j].TotalRevenue {
+			return cats[i].Category < cats[j].Category
+		}
+		return cats[i].TotalRevenue > cats[j].TotalRevenue
+	})
+
+	// Sort category-by-month by category, then chronologically
+	monthly := make([]models.CategoryMonthly, 0, len(p.monthly))
+	for k, v := range p.monthly {
+		monthly = append(monthly, models.CategoryMonthly{Category: k.C, Month: k.M, TotalRevenue: v.rev, UnitsSold: v.units})
+	}
+	sort.Slice(monthly, func(i, j int) bool {
+		if monthly[i].Category == monthly[j].Category {
+			return monthly[i].Month < monthly[j].Month
+		}
+		return monthly[i].Category < monthly[j].Category
+	})
+
+	// Sort category-by-region by category, then by revenue (desc)
+	regions := make([]models.CategoryRegion, 0, len(p.region))
+	for k, v := range p.region {
+		regions = append(regions, models.CategoryRegion{Category: k.C, Region: k.R, TotalRevenue: v.rev, UnitsSold: v.units})
+	}
+	sort.Slice(regions, func(i, j int) bool {
+		if regions[i].Category != regions[j].Category {
+			return regions[i].Category < regions[j].Category
+		}
+		if regions[i].TotalRevenue == regions[j].TotalRevenue {
+			return regions[i].Region < regions[j].Region
+		}
+		return regions[i].TotalRevenue > regions[j].TotalRevenue
+	})
+
+	return cats, monthly, regions
+}
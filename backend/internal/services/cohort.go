@@ -0,0 +1,69 @@
+package services
+
+import (
+	"sort"
+	"time"
+
+	"github.com/GimhaniHM/backend/internal/models"
+)
+
+// monthIndex numbers calendar months consecutively so month offsets can be
+// computed with a subtraction
+func monthIndex(t time.Time) int {
+	return t.Year()*12 + int(t.Month()) - 1
+}
+
+// monthLabel formats a month index as YYYY-MM
+func monthLabel(idx int) string {
+	return time.Date(idx/12, time.Month(idx%12+1), 1, 0, 0, 0, 0, time.UTC).Format("2006-01")
+}
+
+// cohorts groups customers by first-purchase month and builds retention and
+// revenue matrices. Rows run up to the last month present in the data.
+// Customers whose first purchase has no date are left out, since a year-1
+// cohort would allocate tens of thousands of empty months.
+func (p customerPart) cohorts() []models.Cohort {
+	last := 0
+	for _, acc := range p {
+		if m := monthIndex(acc.last); m > last {
+			last = m
+		}
+	}
+
+	rows := make(map[int]*models.Cohort)
+	for _, acc := range p {
+		if acc.first.IsZero() {
+			continue
+		}
+		start := monthIndex(acc.first)
+		row := rows[start]
+		if row == nil {
+			n := last - start + 1
+			row = &models.Cohort{
+				Cohort:    monthLabel(start),
+				Active:    make([]int, n),
+				Retention: make([]float64, n),
+				Revenue:   make([]float64, n),
+			}
+			rows[start] = row
+		}
+		row.Customers++
+		for m, rev := range acc.months {
+			row.Active[m-start]++
+			row.Revenue[m-start] += rev
+		}
+	}
+
+	// Convert active counts into retention percentages
+	out := make([]models.Cohort, 0, len(rows))
+	for _, row := range rows {
+		for i, n := range row.Active {
+			row.Retention[i] = float64(n) * 100 / float64(row.Customers)
+		}
+		out = append(out, *row)
+	}
+
+	// Sort cohorts chronologically
+	sort.Slice(out, func(i, j int) bool { return out[i].Cohort < out[j].Cohort })
+	return out
+}
@@ -3,10 +3,10 @@ package services
 import (
 	"bufio"
 	"encoding/csv"
+	"fmt"
 	"io"
 	"os"
 	"sort"
-	"strconv"
 	"sync"
 	"time"
 
@@ -17,6 +17,7 @@ import (
 type ConcurrentAggregator struct {
 	filePath string
 	workers  int
+	opts     Options
 }
 
 // holds the final aggregated results to be returned
@@ -25,11 +26,49 @@ type Insights struct {
 	TopProducts    []models.ProductFrequency
 	MonthlySales   []models.MonthlySales
 	RegionRevenue  []models.RegionRevenue
+	Countries      []models.CountrySummary
+
+	CategoryRevenue []models.CategoryRevenue
+	CategoryMonthly []models.CategoryMonthly
+	CategoryRegions []models.CategoryRegion
+
+	Customers        map[string]models.CustomerProfile
+	CustomerSegments []models.CustomerSegment
+	Cohorts          []models.Cohort
+
+	Baskets *BasketIndex
+
+	Inventory   []models.InventoryStatus
+	StockSeries map[string][]models.StockPoint
+
+	ProductLifecycle []models.ProductLifecycle
+	LaunchCohorts    []models.LaunchCohort
+
+	ProductPareto  *Pareto
+	CustomerPareto *Pareto
+
+	ProductPrices  []models.ProductPrice
+	PriceChanges   []models.PriceChange
+	CategoryPrices []models.CategoryPrice
+	PriceBands     map[string][]models.PriceBand // by category, "" for all
+
+	Distributions *DistributionIndex
+	Series        *SeriesIndex
+	Cube          *CubeIndex
+	Drill         *DrillIndex
+	Products      *ProductIndex
+	Ingest        models.IngestReport
 }
 
 // creates and returns a new ConcurrentAggregator instance
 func NewConcurrentAggregator(path string, workers int) *ConcurrentAggregator {
-	return &ConcurrentAggregator{filePath: path, workers: workers}
+	return &ConcurrentAggregator{filePath: path, workers: workers, opts: DefaultOptions()}
+}
+
+// WithOptions replaces the aggregation options and returns ca for chaining
+func (ca *ConcurrentAggregator) WithOptions(opts Options) *ConcurrentAggregator {
+	ca.opts = opts
+	return ca
 }
 
 // Run reads the CSV, processes it concurrently, aggregates results, and returns insight
@@ -41,12 +80,28 @@ func (ca *ConcurrentAggregator) Run() (Insights, error) {
 	}
 	defer f.Close()
 	rdr := csv.NewReader(bufio.NewReader(f))
-	if _, err := rdr.Read(); err != nil {
+	if ca.opts.Schema.Delimiter != 0 {
+		rdr.Comma = ca.opts.Schema.Delimiter
+	}
+	header, err := rdr.Read()
+	if err != nil {
 		return Insights{}, err
 	}
+	layout, err := ca.opts.Schema.Layout(header)
+	if err != nil {
+		return Insights{}, fmt.Errorf("%s: %w", ca.filePath, err)
+	}
+
+	// An approximate top-k keeps no per-product state besides its sketch, so
+	// memory stays bounded however many products there are
+	perProduct := ca.opts.TopKCapacity == 0
 
-	// Setup channel & partials
-	records := make(chan []string, ca.workers*2)
+	// Setup one channel per worker & partials. Records are sharded by user so
+	// each worker sees complete baskets (same user, same date).
+	records := make([]chan []string, ca.workers)
+	for i := range records {
+		records[i] = make(chan []string, 64)
+	}
 	var wg sync.WaitGroup
 
 	// Structure for partial aggregation results
@@ -55,12 +110,27 @@ func (ca *ConcurrentAggregator) Run() (Insights, error) {
 			rev float64
 			cnt int
 		}
-		prod   map[string]struct{ cnt, stock int }
+		prod   *productCounter
 		month  map[string]int
 		region map[string]struct {
 			rev  float64
 			sold int
 		}
+		countryTot map[string]struct {
+			rev        float64
+			cnt, units int
+		}
+		countryUsers distinctGroups
+		regionUsers  distinctGroups
+		monthUsers   distinctGroups
+		category     *categoryPart
+		customers    customerPart
+		baskets      *basketPart
+		products     productPart
+		dists        distPart
+		series       seriesPart
+		cube         cubePart
+		invalid      int // rows with an unparseable date or number
 	}
 	partials := make([]part, ca.workers)
 
@@ -74,43 +144,97 @@ func (ca *ConcurrentAggregator) Run() (Insights, error) {
 			rev float64
 			cnt int
 		})
-		p.prod = make(map[string]struct{ cnt, stock int })
+		p.prod = newProductCounter(ca.opts)
 		p.month = make(map[string]int)
 		p.region = make(map[string]struct {
 			rev  float64
 			sold int
 		})
+		p.countryTot = make(map[string]struct {
+			rev        float64
+			cnt, units int
+		})
+		p.countryUsers = make(distinctGroups)
+		p.regionUsers = make(distinctGroups)
+		p.monthUsers = make(distinctGroups)
+		p.category = newCategoryPart(ca.opts)
+		p.customers = make(customerPart)
+		if perProduct {
+			p.products = make(productPart)
+		}
+		p.dists = newDistPart()
+		p.series = make(seriesPart)
+		p.cube = newCubePart()
+		p.baskets = newBasketPart(ca.opts)
 
 		// Process records
-		for rec := range records {
-			qty, _ := strconv.Atoi(rec[9])
-			price, _ := strconv.ParseFloat(rec[8], 64)
-			total := float64(qty) * price
-			dt, _ := time.Parse("2006-01-02", rec[1])
-			mon := dt.Format("2006-01")
-
-			// Aggregate by country + product
-			cp := struct{ C, P string }{rec[3], rec[6]}
-			cv := p.country[cp]
-			cv.rev += total
-			cv.cnt++
-			p.country[cp] = cv
-
-			// Aggregate product purchases and stock quantity
-			pv := p.prod[rec[6]]
-			pv.cnt += qty
-			st, _ := strconv.Atoi(rec[11])
-			pv.stock = st
-			p.prod[rec[6]] = pv
+		for rec := range records[idx] {
+			t, err := layout.Parse(rec)
+			if err != nil {
+				p.invalid++
+				continue
+			}
+			mon := t.TransactionDate.Format("2006-01")
+
+			// Aggregate by country + product ID
+			if perProduct {
+				cp := struct{ C, P string }{t.Country, t.ProductID}
+				cv := p.country[cp]
+				cv.rev += t.TotalPrice
+				cv.cnt++
+				p.country[cp] = cv
+			}
+
+			// Aggregate product purchases and latest stock quantity
+			p.prod.add(t)
 
 			// Aggregate monthly sales
-			p.month[mon] += qty
+			p.month[mon] += t.Quantity
 
 			// Aggregate regional revenue and quantity sold
-			rv := p.region[rec[4]]
-			rv.rev += total
-			rv.sold += qty
-			p.region[rec[4]] = rv
+			rv := p.region[t.Region]
+			rv.rev += t.TotalPrice
+			rv.sold += t.Quantity
+			p.region[t.Region] = rv
+
+			// Aggregate country totals
+			ct := p.countryTot[t.Country]
+			ct.rev += t.TotalPrice
+			ct.cnt++
+			ct.units += t.Quantity
+			p.countryTot[t.Country] = ct
+
+			// Track distinct customers per country, region and month
+			p.countryUsers.add(ca.opts, t.Country, t.UserID)
+			p.regionUsers.add(ca.opts, t.Region, t.UserID)
+			p.monthUsers.add(ca.opts, mon, t.UserID)
+
+			// Aggregate category totals and breakdowns
+			p.category.add(t, mon)
+
+			// Accumulate per-customer purchase history
+			p.customers.add(t)
+
+			// Track order value and quantity distributions
+			p.dists.add(ca.opts, t, mon)
+
+			// Daily revenue and units per country, region and category
+			p.series.add(t)
+
+			// Leaf cells of the geography and time hierarchies for rollups
+			leaf := t.ProductID
+			if !perProduct {
+				leaf = ""
+			}
+			p.cube.add(t, mon, leaf)
+
+			// Accumulate per-product stock and sales history
+			if perProduct {
+				p.products.add(t)
+			}
+
+			// Collect purchase lines for basket mining
+			p.baskets.add(t)
 		}
 	}
 
@@ -120,33 +244,70 @@ func (ca *ConcurrentAggregator) Run() (Insights, error) {
 		go worker(i)
 	}
 
-	// Feed records to workers concurrently
+	// Feed records to workers concurrently, counting rows for the ingest
+	// report (read once the workers are done)
+	var read, skipped int
 	go func() {
-		defer close(records)
+		defer func() {
+			for _, ch := range records {
+				close(ch)
+			}
+		}()
 		for {
 			rec, err := rdr.Read()
 			if err == io.EOF {
 				return
 			}
 			if err != nil {
+				skipped++
 				continue
 			}
-			records <- rec
+			read++
+			records[shardOf(layout.UserID(rec), ca.workers)] <- rec
 		}
 	}()
 	wg.Wait()
 
+	// Mine every worker's baskets into one pair table
+	miner := newBasketMiner(ca.opts)
+	for i, p := range partials {
+		if err := miner.add(p.baskets); err != nil {
+			for _, rest := range partials[i+1:] {
+				rest.baskets.cleanup()
+			}
+			return Insights{}, err
+		}
+		partials[i].baskets = nil
+	}
+
 	// Combine all partial results into final maps
 	countryMap := make(map[struct{ C, P string }]struct {
 		rev float64
 		cnt int
 	})
-	prodMap := make(map[string]struct{ cnt, stock int })
+	prodCounts := newProductCounter(ca.opts)
 	monthMap := make(map[string]int)
 	regionMap := make(map[string]struct {
 		rev  float64
 		sold int
 	})
+	countryTot := make(map[string]struct {
+		rev        float64
+		cnt, units int
+	})
+	countryUsers := make(distinctGroups)
+	regionUsers := make(distinctGroups)
+	monthUsers := make(distinctGroups)
+	categories := newCategoryPart(ca.opts)
+	customers := make(customerPart)
+	var products productPart
+	if perProduct {
+		products = make(productPart)
+	}
+	dists := newDistPart()
+	series := make(seriesPart)
+	cube := newCubePart()
+	invalid := 0
 	for _, p := range partials {
 		for k, v := range p.country {
 			cv := countryMap[k]
@@ -154,14 +315,7 @@ func (ca *ConcurrentAggregator) Run() (Insights, error) {
 			cv.cnt += v.cnt
 			countryMap[k] = cv
 		}
-		for k, v := range p.prod {
-			pv := prodMap[k]
-			pv.cnt += v.cnt
-			if v.stock > pv.stock {
-				pv.stock = v.stock
-			}
-			prodMap[k] = pv
-		}
+		prodCounts.merge(p.prod)
 		for k, v := range p.month {
 			monthMap[k] += v
 		}
@@ -171,35 +325,44 @@ func (ca *ConcurrentAggregator) Run() (Insights, error) {
 			rv.sold += v.sold
 			regionMap[k] = rv
 		}
+		for k, v := range p.countryTot {
+			ct := countryTot[k]
+			ct.rev += v.rev
+			ct.cnt += v.cnt
+			ct.units += v.units
+			countryTot[k] = ct
+		}
+		countryUsers.merge(p.countryUsers)
+		regionUsers.merge(p.regionUsers)
+		monthUsers.merge(p.monthUsers)
+		categories.merge(p.category)
+		customers.merge(p.customers)
+		products.merge(p.products)
+		dists.merge(p.dists)
+		series.merge(p.series)
+		cube.merge(p.cube)
+		invalid += p.invalid
 	}
 
 	//// Convert combined maps into sorted slices
 	// Sort country-product revenue
 	cr := make([]models.CountryRevenue, 0, len(countryMap))
 	for k, v := range countryMap {
-		cr = append(cr, models.CountryRevenue{Country: k.C, ProductName: k.P, TotalRevenue: v.rev, TransactionCount: v.cnt})
-	}
-	sort.Slice(cr, func(i, j int) bool { return cr[i].TotalRevenue > cr[j].TotalRevenue })
-
-	// Sort top products by purchase count
-	tp := make([]models.ProductFrequency, 0, len(prodMap))
-	for k, v := range prodMap {
-		tp = append(tp, models.ProductFrequency{ProductName: k, PurchaseCount: v.cnt, StockQuantity: v.stock})
-	}
-	sort.Slice(tp, func(i, j int) bool {
-		if tp[i].PurchaseCount == tp[j].PurchaseCount {
-			return tp[i].ProductName > tp[j].ProductName
+		row := models.CountryRevenue{Country: k.C, ProductID: k.P, TotalRevenue: v.rev, TransactionCount: v.cnt}
+		if acc := products[k.P]; acc != nil {
+			row.ProductName, row.Category = acc.name, acc.category
 		}
-		return tp[i].PurchaseCount > tp[j].PurchaseCount
-	})
-	if len(tp) > 20 {
-		tp = tp[:20]
+		cr = append(cr, row)
 	}
+	sort.Slice(cr, func(i, j int) bool { return countryRevenueLess(cr[i], cr[j]) })
+
+	// Top products by purchase count
+	tp := prodCounts.top(ca.opts.TopProductsCap, products)
 
 	// Sort monthly sales
 	ms := make([]models.MonthlySales, 0, len(monthMap))
 	for k, v := range monthMap {
-		ms = append(ms, models.MonthlySales{Month: k, SalesVolume: v})
+		ms = append(ms, models.MonthlySales{Month: k, SalesVolume: v, UniqueCustomers: monthUsers.count(k)})
 	}
 	sort.Slice(ms, func(i, j int) bool {
 		ti, _ := time.Parse("2006-01", ms[i].Month)
@@ -210,12 +373,113 @@ func (ca *ConcurrentAggregator) Run() (Insights, error) {
 	// Sort region revenue in descending order
 	rr := make([]models.RegionRevenue, 0, len(regionMap))
 	for k, v := range regionMap {
-		rr = append(rr, models.RegionRevenue{Region: k, TotalRevenue: v.rev, ItemsSold: v.sold})
+		rr = append(rr, models.RegionRevenue{Region: k, TotalRevenue: v.rev, ItemsSold: v.sold, UniqueCustomers: regionUsers.count(k)})
 	}
-	sort.Slice(rr, func(i, j int) bool { return rr[i].TotalRevenue > rr[j].TotalRevenue })
-	if len(rr) > 30 {
-		rr = rr[:30]
+	sort.Slice(rr, func(i, j int) bool { return regionRevenueLess(rr[i], rr[j]) })
+	if n := ca.opts.TopRegionsCap; n > 0 && len(rr) > n {
+		rr = rr[:n]
 	}
 
-	return Insights{cr, tp, ms, rr}, nil
+	// Sort country summaries by revenue (desc)
+	cs := make([]models.CountrySummary, 0, len(countryTot))
+	for k, v := range countryTot {
+		cs = append(cs, models.CountrySummary{Country: k, TotalRevenue: v.rev, TransactionCount: v.cnt, ItemsSold: v.units, UniqueCustomers: countryUsers.count(k)})
+	}
+	sort.Slice(cs, func(i, j int) bool {
+		if cs[i].TotalRevenue == cs[j].TotalRevenue {
+			return cs[i].Country < cs[j].Country
+		}
+		return cs[i].TotalRevenue > cs[j].TotalRevenue
+	})
+
+	// Sort category totals and breakdowns
+	cats, catMonthly, catRegions := categories.results()
+
+	// Score customers and summarise RFM segments
+	profiles, segments := customers.results(ca.opts)
+
+	// Build monthly acquisition cohorts
+	cohorts := customers.cohorts()
+
+	// Index co-purchased product pairs
+	basketIdx := newBasketIndex(miner.results(), ca.opts.BasketMinCount)
+
+	// Derive stock levels, sales velocity and days of cover
+	inventory, stockSeries := products.inventory(ca.opts)
+
+	// Measure product age, time to first sale and launch performance
+	lifecycle, launches := products.lifecycle()
+
+	// Rank products and customers by revenue contribution
+	productPareto, customerPareto := buildParetos(products, customers, ca.opts)
+
+	// Summarise selling prices, price changes and price bands
+	productPrices, priceChanges := products.prices(ca.opts)
+	categoryPrices, priceBands := categories.priceResults()
+
+	// Index time series and drill down from countries and regions
+	seriesIndex := &SeriesIndex{
+		DayWindow:   ca.opts.AnomalyDayWindow,
+		MonthWindow: ca.opts.AnomalyMonthWindow,
+		Threshold:   ca.opts.AnomalyThreshold,
+		days:        series,
+	}
+	drill := buildDrillIndex(cube["geo"], products, seriesIndex, countryUsers, regionUsers)
+
+	// Index products for detail lookups and search
+	catalog := newProductIndex(products, inventory)
+
+	ingest := products.ingestReport(read-invalid, skipped, invalid)
+	if !perProduct {
+		ingest.Warnings = append(ingest.Warnings, "top products are ranked approximately; per-product insights (country revenue by product, inventory, lifecycle, prices, product Pareto and product rollups) are left empty")
+	}
+
+	return Insights{
+		CountryRevenue:  cr,
+		TopProducts:     tp,
+		MonthlySales:    ms,
+		RegionRevenue:   rr,
+		Countries:       cs,
+		CategoryRevenue: cats,
+		CategoryMonthly: catMonthly,
+		CategoryRegions: catRegions,
+
+		Customers:        profiles,
+		CustomerSegments: segments,
+		Cohorts:          cohorts,
+
+		Baskets: basketIdx,
+
+		Inventory:   inventory,
+		StockSeries: stockSeries,
+
+		ProductLifecycle: lifecycle,
+		LaunchCohorts:    launches,
+
+		ProductPareto:  productPareto,
+		CustomerPareto: customerPareto,
+
+		ProductPrices:  productPrices,
+		PriceChanges:   priceChanges,
+		CategoryPrices: categoryPrices,
+		PriceBands:     priceBands,
+
+		Distributions: &DistributionIndex{groups: dists},
+		Series:        seriesIndex,
+		Cube:          newCubeIndex(cube, products),
+		Drill:         drill,
+		Products:      catalog,
+		Ingest:        ingest,
+	}, nil
+}
+
+// shardOf picks the worker for a user ID (FNV-1a), so all of a user's
+// records are processed by the same worker
+func shardOf(user string, workers int) int {
+	h := uint32(2166136261)
+	for i := 0; i < len(user); i++ {
+		h ^= uint32(user[i])
+		h *= 16777619
+	}
+	return int(h % uint32(workers))
 }
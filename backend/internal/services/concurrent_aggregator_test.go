@@ -0,0 +1,500 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/GimhaniHM/backend/internal/models"
+	"github.com/GimhaniHM/backend/internal/utils"
+)
+
+const csvHeader = "transaction_id,transaction_date,user_id,country,region,product_id,product_name,category,price,quantity,total_price,stock_quantity,added_date"
+
+// writes the given rows (without header) to a temporary CSV file
+func writeCSV(t *testing.T, rows ...string) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "test.csv")
+	content := csvHeader + "\n" + strings.Join(rows, "\n") + "\n"
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+// TestRunCategoryInsights checks that category totals, shares and breakdowns
+// are computed in the concurrent pass
+func TestRunCategoryInsights(t *testing.T) {
+	file := writeCSV(t,
+		"T1,2024-01-05,U1,USA,West,P1,Prod1,Toys,10,2,20,5,2023-12-01",
+		"T2,2024-01-20,U2,USA,East,P2,Prod2,Toys,5,2,10,5,2023-12-01",
+		"T3,2024-02-03,U1,USA,West,P1,Prod1,Toys,10,1,10,4,2023-12-01",
+		"T4,2024-02-10,U3,UK,North,P3,Prod3,Books,20,1,20,9,2023-11-01",
+	)
+
+	got, err := NewConcurrentAggregator(file, 3).Run()
+	if err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+
+	wantCats := []models.CategoryRevenue{
+		{Category: "Toys", TotalRevenue: 40, UnitsSold: 5, TransactionCount: 3, DistinctProducts: 2, RevenueShare: 40.0 / 60.0},
+		{Category: "Books", TotalRevenue: 20, UnitsSold: 1, TransactionCount: 1, DistinctProducts: 1, RevenueShare: 20.0 / 60.0},
+	}
+	if !reflect.DeepEqual(got.CategoryRevenue, wantCats) {
+		t.Errorf("CategoryRevenue = %+v; want %+v", got.CategoryRevenue, wantCats)
+	}
+
+	wantMonthly := []models.CategoryMonthly{
+		{Category: "Books", Month: "2024-02", TotalRevenue: 20, UnitsSold: 1},
+		{Category: "Toys", Month: "2024-01", TotalRevenue: 30, UnitsSold: 4},
+		{Category: "Toys", Month: "2024-02", TotalRevenue: 10, UnitsSold: 1},
+	}
+	if !reflect.DeepEqual(got.CategoryMonthly, wantMonthly) {
+		t.Errorf("CategoryMonthly = %+v; want %+v", got.CategoryMonthly, wantMonthly)
+	}
+
+	wantRegions := []models.CategoryRegion{
+		{Category: "Books", Region: "North", TotalRevenue: 20, UnitsSold: 1},
+		{Category: "Toys", Region: "West", TotalRevenue: 30, UnitsSold: 3},
+		{Category: "Toys", Region: "East", TotalRevenue: 10, UnitsSold: 2},
+	}
+	if !reflect.DeepEqual(got.CategoryRegions, wantRegions) {
+		t.Errorf("CategoryRegions = %+v; want %+v", got.CategoryRegions, wantRegions)
+	}
+}
+
+// TestRunUniqueCustomers checks distinct customers per country, region and
+// month in both HyperLogLog and exact modes
+func TestRunUniqueCustomers(t *testing.T) {
+	file := writeCSV(t,
+		"T1,2024-01-05,U1,USA,West,P1,Prod1,Toys,10,1,10,5,2023-12-01",
+		"T2,2024-01-06,U1,USA,West,P2,Prod2,Toys,10,1,10,5,2023-12-01",
+		"T3,2024-01-07,U2,USA,East,P1,Prod1,Toys,10,1,10,5,2023-12-01",
+		"T4,2024-02-01,U3,UK,West,P1,Prod1,Toys,10,1,10,5,2023-12-01",
+	)
+
+	for _, exact := range []bool{false, true} {
+		opts := DefaultOptions()
+		opts.ExactDistinct = exact
+		got, err := NewConcurrentAggregator(file, 2).WithOptions(opts).Run()
+		if err != nil {
+			t.Fatalf("Run error: %v", err)
+		}
+
+		wantCountries := []models.CountrySummary{
+			{Country: "USA", TotalRevenue: 30, TransactionCount: 3, ItemsSold: 3, UniqueCustomers: 2},
+			{Country: "UK", TotalRevenue: 10, TransactionCount: 1, ItemsSold: 1, UniqueCustomers: 1},
+		}
+		if !reflect.DeepEqual(got.Countries, wantCountries) {
+			t.Errorf("exact=%v Countries = %+v; want %+v", exact, got.Countries, wantCountries)
+		}
+		if got.RegionRevenue[0].Region != "West" || got.RegionRevenue[0].UniqueCustomers != 2 {
+			t.Errorf("exact=%v RegionRevenue[0] = %+v; want West with 2 customers", exact, got.RegionRevenue[0])
+		}
+		if got.MonthlySales[0].UniqueCustomers != 2 || got.MonthlySales[1].UniqueCustomers != 1 {
+			t.Errorf("exact=%v MonthlySales = %+v; want 2 and 1 customers", exact, got.MonthlySales)
+		}
+	}
+}
+
+// TestRunBaskets checks that baskets are grouped by user and date across
+// workers and that association metrics are computed from basket counts
+func TestRunBaskets(t *testing.T) {
+	file := writeCSV(t,
+		"T1,2024-01-05,U1,USA,West,P1,Bread,Food,1,1,1,5,2023-12-01",
+		"T2,2024-01-05,U1,USA,West,P2,Butter,Food,1,1,1,5,2023-12-01",
+		"T3,2024-01-06,U2,USA,West,P1,Bread,Food,1,1,1,5,2023-12-01",
+		"T4,2024-01-06,U2,USA,West,P2,Butter,Food,1,1,1,5,2023-12-01",
+		"T5,2024-01-06,U2,USA,West,P3,Jam,Food,1,1,1,5,2023-12-01",
+		"T6,2024-01-07,U3,USA,West,P1,Bread,Food,1,1,1,5,2023-12-01",
+		"T7,2024-01-08,U3,USA,West,P3,Jam,Food,1,1,1,5,2023-12-01",
+	)
+	// A buffer of one row spills every line to disk
+	for _, buffer := range []int{DefaultOptions().BasketBufferRows, 1} {
+		opts := DefaultOptions()
+		opts.BasketMinCount = 1
+		opts.BasketBufferRows = buffer
+		got, err := NewConcurrentAggregator(file, 4).WithOptions(opts).Run()
+		if err != nil {
+			t.Fatalf("buffer %d: Run error: %v", buffer, err)
+		}
+
+		b := got.Baskets
+		if b.Baskets != 4 || b.Approximate {
+			t.Fatalf("buffer %d: Baskets = %d (approximate %v); want 4 exact", buffer, b.Baskets, b.Approximate)
+		}
+		top := b.TopPairs(1)
+		want := models.ProductPair{
+			ProductA: "P1", ProductNameA: "Bread", ProductB: "P2", ProductNameB: "Butter",
+			Baskets: 2, Support: 0.5, ConfidenceAB: 2.0 / 3.0, ConfidenceBA: 1, Lift: 4.0 / 3.0,
+		}
+		if len(top) != 1 || !reflect.DeepEqual(top[0], want) {
+			t.Errorf("buffer %d: TopPairs(1) = %+v; want %+v", buffer, top, want)
+		}
+
+		related, ok := b.Related("P3", 10)
+		if !ok || len(related) != 2 || related[0].ProductID != "P2" || related[0].Confidence != 0.5 {
+			t.Errorf("buffer %d: Related(P3) = %+v; want P2 first with confidence 0.5", buffer, related)
+		}
+		if _, ok := b.Related("P9", 10); ok {
+			t.Errorf("buffer %d: Related(P9) found; want unknown product", buffer)
+		}
+	}
+}
+
+// TestRunInventory checks that stock uses the latest reading by transaction
+// date and that days of cover and status are derived from recent velocity
+func TestRunInventory(t *testing.T) {
+	file := writeCSV(t,
+		// P1: latest stock (3) is on the latest date even though it is read first
+		"T1,2024-03-31,U1,USA,West,P1,Prod1,Toys,1,30,30,3,2023-12-01",
+		"T2,2024-03-01,U2,USA,West,P1,Prod1,Toys,1,30,30,50,2023-12-01",
+		"T3,2024-01-10,U3,USA,West,P1,Prod1,Toys,1,30,30,90,2023-12-01",
+		// P2: plenty of stock, one sale in the window
+		"T4,2024-02-15,U1,USA,West,P2,Prod2,Toys,1,1,1,500,2023-12-01",
+		// P3: sold only before the window
+		"T5,2023-06-15,U1,USA,West,P3,Prod3,Toys,1,1,1,5,2023-01-01",
+	)
+	opts := DefaultOptions()
+	opts.VelocityMonths = 2
+	got, err := NewConcurrentAggregator(file, 3).WithOptions(opts).Run()
+	if err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+	if len(got.Inventory) != 3 {
+		t.Fatalf("Inventory = %+v; want 3 products", got.Inventory)
+	}
+
+	// window is 2024-02-01..2024-03-31 (60 days): P1 sold 60 units
+	p1 := got.Inventory[0]
+	if p1.ProductID != "P1" || p1.CurrentStock != 3 || p1.StockAsOf != "2024-03-31" ||
+		p1.DailyVelocity != 1 || p1.DaysOfCover == nil || *p1.DaysOfCover != 3 || p1.Status != StockLow {
+		t.Errorf("Inventory[0] = %+v; want P1 with stock 3, velocity 1, 3 days cover, low_stock", p1)
+	}
+	if p2 := got.Inventory[1]; p2.ProductID != "P2" || p2.Status != StockOver {
+		t.Errorf("Inventory[1] = %+v; want P2 overstock", p2)
+	}
+	if p3 := got.Inventory[2]; p3.ProductID != "P3" || p3.DaysOfCover != nil || p3.Status != StockOver {
+		t.Errorf("Inventory[2] = %+v; want P3 with no cover estimate, overstock", p3)
+	}
+
+	wantSeries := []models.StockPoint{
+		{Month: "2024-01", Stock: 90, AsOf: "2024-01-10", UnitsSold: 30},
+		{Month: "2024-03", Stock: 3, AsOf: "2024-03-31", UnitsSold: 60},
+	}
+	if !reflect.DeepEqual(got.StockSeries["P1"], wantSeries) {
+		t.Errorf("StockSeries[P1] = %+v; want %+v", got.StockSeries["P1"], wantSeries)
+	}
+	if got.TopProducts[0].ProductName != "Prod1" || got.TopProducts[0].StockQuantity != 3 {
+		t.Errorf("TopProducts[0] = %+v; want Prod1 with latest stock 3", got.TopProducts[0])
+	}
+}
+
+// TestRunDistributions checks that order value and quantity digests are
+// built per dimension and merged across workers
+func TestRunDistributions(t *testing.T) {
+	rows := make([]string, 0, 100)
+	for i := 1; i <= 100; i++ {
+		country := "USA"
+		if i%2 == 0 {
+			country = "UK"
+		}
+		rows = append(rows, fmt.Sprintf("T%d,2024-01-05,U%d,%s,West,P1,Prod1,Toys,%d,1,%d,5,2023-12-01", i, i, country, i, i))
+	}
+	got, err := NewConcurrentAggregator(writeCSV(t, rows...), 4).Run()
+	if err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+
+	total, ok := got.Distributions.Distributions("total", "order_value", 4)
+	if !ok || len(total) != 1 {
+		t.Fatalf("Distributions(total) = %+v, %v; want one group", total, ok)
+	}
+	d := total[0]
+	if d.Count != 100 || d.Min != 1 || d.Max != 100 || d.Mean != 50.5 || math.Abs(d.P50-50.5) > 1 || math.Abs(d.P90-90.5) > 1 {
+		t.Errorf("total distribution = %+v; want 100 samples from 1 to 100", d)
+	}
+	if len(d.Histogram) != 4 || d.Histogram[0].Count != 25 {
+		t.Errorf("histogram = %+v; want 4 bins of ~25", d.Histogram)
+	}
+
+	byCountry, _ := got.Distributions.Distributions("country", "quantity", 10)
+	if len(byCountry) != 2 || byCountry[0].Key != "UK" || byCountry[0].Count != 50 || byCountry[0].P99 != 1 {
+		t.Errorf("country distributions = %+v; want UK and USA with 50 samples of quantity 1", byCountry)
+	}
+	if _, ok := got.Distributions.Distributions("city", "quantity", 10); ok {
+		t.Errorf("unknown dimension accepted")
+	}
+}
+
+// TestRunApproximateTopProducts checks the Space-Saving mode ranks the
+// same heavy hitters as exact counting and reports error bounds
+func TestRunApproximateTopProducts(t *testing.T) {
+	rows := make([]string, 0, 600)
+	for i := 0; i < 600; i++ {
+		name := fmt.Sprintf("Rare%d", i)
+		qty := 1
+		if i%4 == 0 {
+			name, qty = fmt.Sprintf("Hot%d", i%3), 5
+		}
+		rows = append(rows, fmt.Sprintf("T%d,2024-01-05,U%d,USA,West,%s,%s,Toys,1,%d,%d,5,2023-12-01", i, i%37, name, name, qty, qty))
+	}
+	path := writeCSV(t, rows...)
+
+	exact, err := NewConcurrentAggregator(path, 3).Run()
+	if err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+	opts := DefaultOptions()
+	opts.TopKCapacity = 20
+	approx, err := NewConcurrentAggregator(path, 3).WithOptions(opts).Run()
+	if err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		e, a := exact.TopProducts[i], approx.TopProducts[i]
+		if e.ProductName != a.ProductName || a.PurchaseCount < e.PurchaseCount || a.PurchaseCount-a.MaxError > e.PurchaseCount {
+			t.Errorf("rank %d: approximate %+v; exact %+v", i+1, a, e)
+		}
+		if e.MaxError != 0 {
+			t.Errorf("exact row %+v reports an error bound", e)
+		}
+	}
+
+	// No per-product state is kept besides the sketch's own labels
+	if len(approx.CountryRevenue) != 0 || len(approx.Inventory) != 0 || len(approx.ProductPrices) != 0 || approx.Ingest.Products != 0 {
+		t.Errorf("approximate run kept per-product insights: %d country rows, %d inventory rows, %d prices, %d products",
+			len(approx.CountryRevenue), len(approx.Inventory), len(approx.ProductPrices), approx.Ingest.Products)
+	}
+	if n := len(approx.Ingest.Warnings); n == 0 || !strings.Contains(approx.Ingest.Warnings[n-1], "per-product insights") {
+		t.Errorf("Warnings = %q; want the per-product insights warning", approx.Ingest.Warnings)
+	}
+	rollup, _ := approx.Cube.Rollup("geo", false)
+	for _, r := range rollup {
+		if r.Level == "country+region+product" {
+			t.Errorf("approximate run has product rollup row %+v", r)
+		}
+	}
+	if got := approx.CategoryRevenue[0].DistinctProducts; math.Abs(float64(got)-453) > 453*0.05 {
+		t.Errorf("DistinctProducts = %d; want about 453", got)
+	}
+}
+
+// TestProductCounterDropsEvictedLabels checks approximate mode only keeps
+// labels for the products the sketch tracks, after adds and merges
+func TestProductCounterDropsEvictedLabels(t *testing.T) {
+	opts := Options{TopKCapacity: 4}
+	a, b := newProductCounter(opts), newProductCounter(opts)
+	for i := 0; i < 50; i++ {
+		id := fmt.Sprintf("P%d", i)
+		a.add(models.Transaction{ProductID: id, ProductName: "A " + id, Quantity: 1})
+		b.add(models.Transaction{ProductID: id, ProductName: "B " + id, Quantity: 2})
+	}
+	a.merge(b)
+	if len(a.labels) > 4 {
+		t.Errorf("len(labels) = %d; want at most 4", len(a.labels))
+	}
+	for id := range a.labels {
+		if !a.approx.Contains(id) {
+			t.Errorf("label kept for untracked %s", id)
+		}
+	}
+	for _, pf := range a.top(0, nil) {
+		if pf.ProductName == "" {
+			t.Errorf("unlabelled top product %+v", pf)
+		}
+	}
+}
+
+// TestRunDrilldown checks country and region drill-downs aggregate every
+// worker's rows into KPIs and child breakdowns
+func TestRunDrilldown(t *testing.T) {
+	file := writeCSV(t,
+		"T1,2024-01-05,U1,USA,West,P1,Prod1,Toys,10,2,20,5,2023-12-01",
+		"T2,2024-03-05,U2,USA,East,P2,Prod2,Toys,30,1,30,5,2023-12-01",
+		"T3,2024-03-06,U1,USA,West,P2,Prod2,Toys,30,1,30,5,2023-12-01",
+		"T4,2024-03-06,U3,UK,West,P1,Prod1,Toys,10,1,10,5,2023-12-01",
+	)
+	got, err := NewConcurrentAggregator(file, 2).Run()
+	if err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+
+	usa, ok := got.Drill.Country("USA", 1)
+	if !ok || usa.TotalRevenue != 80 || usa.UnitsSold != 4 || usa.UniqueCustomers != 2 || usa.ProductCount != 2 || len(usa.Products) != 1 {
+		t.Fatalf("Country(USA) = %+v, %v", usa, ok)
+	}
+	if usa.Products[0].Name != "Prod2" || len(usa.Regions) != 2 || usa.Regions[0].Name != "West" || usa.Regions[0].RevenueShare != 0.625 {
+		t.Errorf("USA breakdowns = products %+v, regions %+v", usa.Products, usa.Regions)
+	}
+	if len(usa.Months) != 3 || usa.Months[1].Month != "2024-02" || usa.Months[2].TotalRevenue != 60 {
+		t.Errorf("USA months = %+v; want Jan-Mar zero-filled", usa.Months)
+	}
+
+	west, ok := got.Drill.Region("West", 20)
+	if !ok || west.TotalRevenue != 60 || len(west.Countries) != 2 || west.Countries[0].Name != "USA" || west.UniqueCustomers != 2 {
+		t.Errorf("Region(West) = %+v, %v", west, ok)
+	}
+	if _, ok := got.Drill.Country("France", 20); ok {
+		t.Errorf("unknown country found")
+	}
+}
+
+// TestRunKeysByProductID checks products sharing a name stay separate,
+// renamed products keep their latest name, and both are reported
+func TestRunKeysByProductID(t *testing.T) {
+	file := writeCSV(t,
+		"T1,2024-01-05,U1,USA,West,P1,Widget,Toys,10,2,20,5,2023-12-01",
+		"T2,2024-01-06,U2,USA,West,P2,Widget,Tools,10,1,10,5,2023-12-01",
+		"T3,2024-01-07,U3,USA,West,P2,Widget Pro,Tools,10,1,10,4,2023-12-01",
+		"T4,2024-01-08,U4,USA,West,P3,Gadget,Toys",
+	)
+	got, err := NewConcurrentAggregator(file, 2).Run()
+	if err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+
+	want := []models.ProductFrequency{
+		{ProductID: "P2", ProductName: "Widget Pro", Category: "Tools", PurchaseCount: 2, StockQuantity: 4},
+		{ProductID: "P1", ProductName: "Widget", Category: "Toys", PurchaseCount: 2, StockQuantity: 5},
+	}
+	if !reflect.DeepEqual(got.TopProducts, want) {
+		t.Errorf("TopProducts = %+v; want %+v", got.TopProducts, want)
+	}
+	if len(got.CountryRevenue) != 2 {
+		t.Errorf("CountryRevenue = %+v; want one row per product ID", got.CountryRevenue)
+	}
+
+	r := got.Ingest
+	if r.RowsRead != 3 || r.RowsSkipped != 1 || r.Products != 2 || len(r.Warnings) != 3 {
+		t.Errorf("Ingest = %+v; want 3 read, 1 skipped, 2 products, 3 warnings", r)
+	}
+	if len(r.NameCollisions) != 1 || !reflect.DeepEqual(r.NameCollisions[0].ProductIDs, []string{"P1", "P2"}) {
+		t.Errorf("NameCollisions = %+v; want Widget shared by P1 and P2", r.NameCollisions)
+	}
+	if len(r.RenamedProducts) != 1 || r.RenamedProducts[0].CurrentName != "Widget Pro" {
+		t.Errorf("RenamedProducts = %+v; want P2 renamed to Widget Pro", r.RenamedProducts)
+	}
+}
+
+// TestRunTopCaps checks top products and regions keep the full ranking by
+// default and are truncated to the configured caps
+func TestRunTopCaps(t *testing.T) {
+	rows := make([]string, 0, 40)
+	for i := 0; i < 40; i++ {
+		rows = append(rows, fmt.Sprintf("T%d,2024-01-05,U%d,USA,R%d,P%d,Prod%d,Toys,1,%d,%d,5,2023-12-01", i, i, i, i, i, i+1, i+1))
+	}
+	path := writeCSV(t, rows...)
+
+	all, err := NewConcurrentAggregator(path, 3).Run()
+	if err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+	if len(all.TopProducts) != 40 || len(all.RegionRevenue) != 40 {
+		t.Fatalf("got %d products, %d regions; want 40 of each", len(all.TopProducts), len(all.RegionRevenue))
+	}
+	if all.TopProducts[0].ProductID != "P39" || all.RegionRevenue[39].Region != "R0" {
+		t.Errorf("ranking = %+v ... %+v", all.TopProducts[0], all.RegionRevenue[39])
+	}
+
+	opts := DefaultOptions()
+	opts.TopProductsCap, opts.TopRegionsCap = 5, 7
+	capped, err := NewConcurrentAggregator(path, 3).WithOptions(opts).Run()
+	if err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+	if !reflect.DeepEqual(capped.TopProducts, all.TopProducts[:5]) || !reflect.DeepEqual(capped.RegionRevenue, all.RegionRevenue[:7]) {
+		t.Errorf("capped = %+v, %+v", capped.TopProducts, capped.RegionRevenue)
+	}
+}
+
+// TestRunSchema checks that a mapped schema reads a CSV with renamed and
+// reordered columns, another delimiter and another date format
+func TestRunSchema(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "mapped.csv")
+	content := "customer;day;product;name;cat;country;region;unit_price;qty\n" +
+		"U1;05/01/2024;P1;Prod1;Toys;USA;West;10;2\n" +
+		"U2;20/02/2024;P1;Prod1;Toys;USA;West;10;1\n"
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := DefaultOptions()
+	opts.Schema = utils.Schema{
+		Columns: map[string]string{
+			"user_id": "customer", "transaction_date": "day", "product_id": "product",
+			"product_name": "name", "category": "cat", "price": "unit_price", "quantity": "qty",
+		},
+		DateFormat: "02/01/2006",
+		Delimiter:  ';',
+	}
+	ins, err := NewConcurrentAggregator(file, 2).WithOptions(opts).Run()
+	if err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+
+	var months []string
+	for _, m := range ins.MonthlySales {
+		months = append(months, fmt.Sprintf("%s:%d", m.Month, m.SalesVolume))
+	}
+	if want := []string{"2024-01:2", "2024-02:1"}; !reflect.DeepEqual(months, want) {
+		t.Errorf("monthly sales = %v; want %v", months, want)
+	}
+	if ins.Ingest.RowsSkipped != 0 {
+		t.Errorf("RowsSkipped = %d; want 0", ins.Ingest.RowsSkipped)
+	}
+
+	// A required column missing from the header fails the run
+	opts.Schema.Columns["user_id"] = "client"
+	if _, err := NewConcurrentAggregator(file, 2).WithOptions(opts).Run(); err == nil {
+		t.Error("Run with a missing column succeeded; want an error")
+	}
+}
+
+// TestRunSeriesIgnoresBadDates checks that a row with an unparseable date
+// does not stretch the zero-filled series back to year 1
+func TestRunSeriesIgnoresBadDates(t *testing.T) {
+	path := writeCSV(t,
+		"T1,2024-01-05,U1,USA,West,P1,Prod1,Toys,10,2,20,5,2023-12-01",
+		"T2,bad-date,U2,USA,West,P1,Prod1,Toys,10,1,10,5,2023-12-01",
+		"T3,2024-01-07,U3,USA,West,P1,Prod1,Toys,10,1,10,5,2023-12-01",
+	)
+	ins, err := NewConcurrentAggregator(path, 2).Run()
+	if err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+
+	labels, values, ok := ins.Series.Series("total", "all", GranularityDay, MetricRevenue)
+	if !ok || !reflect.DeepEqual(labels, []string{"2024-01-05", "2024-01-06", "2024-01-07"}) || !reflect.DeepEqual(values, []float64{20, 0, 10}) {
+		t.Errorf("Series(day) = %v %v, ok %v; want 3 days from 2024-01-05", labels, values, ok)
+	}
+}
+
+// TestRunSkipsUnparseableRows checks that rows with a bad date or number are
+// left out of the insights and counted in the ingest report
+func TestRunSkipsUnparseableRows(t *testing.T) {
+	path := writeCSV(t,
+		"T1,2024-01-05,U1,USA,West,P1,Prod1,Toys,10,2,20,5,2023-12-01",
+		"T2,bad-date,U2,USA,West,P1,Prod1,Toys,10,1,10,5,2023-12-01",
+		"T3,2024-01-07,U3,USA,West,P1,Prod1,Toys,ten,1,10,5,2023-12-01",
+		"T4,2024-01-08,U4,USA,West,P1,Prod1,Toys,10,1,10,,",
+	)
+	ins, err := NewConcurrentAggregator(path, 2).Run()
+	if err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+
+	r := ins.Ingest
+	if r.RowsRead != 2 || r.RowsSkipped != 2 || !reflect.DeepEqual(r.Warnings, []string{"2 rows with an unparseable date or number were skipped"}) {
+		t.Errorf("Ingest = %+v; want 2 read, 2 skipped with a warning", r)
+	}
+	if len(ins.Cohorts) != 1 || ins.Cohorts[0].Customers != 2 {
+		t.Errorf("Cohorts = %+v; want one cohort of the 2 valid customers", ins.Cohorts)
+	}
+}
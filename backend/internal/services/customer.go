@@ -0,0 +1,249 @@
+package services
+
+import (
+	"sort"
+	"time"
+
+	"github.com/GimhaniHM/backend/internal/models"
+)
+
+// Segment maps a range of RFM scores to a named customer segment.
+// Ranges are inclusive; a range of 0-0 matches any score.
+type Segment struct {
+	Name       string
+	RMin, RMax int
+	FMin, FMax int
+	MMin, MMax int
+}
+
+// matches reports whether the given scores fall inside the segment
+func (s Segment) matches(r, f, m int) bool {
+	in := func(v, lo, hi int) bool {
+		return (lo == 0 && hi == 0) || (v >= lo && v <= hi)
+	}
+	return in(r, s.RMin, s.RMax) && in(f, s.FMin, s.FMax) && in(m, s.MMin, s.MMax)
+}
+
+// DefaultSegments is the standard RFM segmentation on a 1-5 scale.
+// Segments are evaluated in order and the first match wins.
+func DefaultSegments() []Segment {
+	return []Segment{
+		{Name: "champions", RMin: 4, RMax: 5, FMin: 4, FMax: 5},
+		{Name: "loyal", RMin: 3, RMax: 5, FMin: 3, FMax: 5},
+		{Name: "cant_lose", RMin: 1, RMax: 1, FMin: 5, FMax: 5},
+		{Name: "at_risk", RMin: 1, RMax: 2, FMin: 3, FMax: 5},
+		{Name: "new_customers", RMin: 5, RMax: 5, FMin: 1, FMax: 1},
+		{Name: "potential_loyalists", RMin: 3, RMax: 5, FMin: 1, FMax: 2},
+		{Name: "hibernating", RMin: 2, RMax: 2, FMin: 1, FMax: 2},
+		{Name: "lost", RMin: 1, RMax: 1, FMin: 1, FMax: 2},
+	}
+}
+
+// segment name used when no configured segment matches
+const otherSegment = "others"
+
+// running purchase history for one customer
+type customerAcc struct {
+	first, last time.Time
+	orders      map[uint64]struct{} // distinct transaction IDs (see hashKey)
+	lines       int                 // rows without a transaction ID, one order each
+	monetary    float64
+	units       int
+	months      map[int]float64 // revenue by month index (see monthIndex)
+}
+
+// customerPart holds one worker's per-user accumulators
+type customerPart map[string]*customerAcc
+
+// add folds a single transaction into the user's accumulator
+func (p customerPart) add(t models.Transaction) {
+	if t.UserID == "" {
+		return
+	}
+	acc := p[t.UserID]
+	if acc == nil {
+		acc = &customerAcc{first: t.TransactionDate, last: t.TransactionDate, orders: make(map[uint64]struct{}), months: make(map[int]float64)}
+		p[t.UserID] = acc
+	}
+	if t.TransactionDate.Before(acc.first) {
+		acc.first = t.TransactionDate
+	}
+	if t.TransactionDate.After(acc.last) {
+		acc.last = t.TransactionDate
+	}
+	if t.TransactionID != "" {
+		acc.orders[hashKey(t.TransactionID)] = struct{}{}
+	} else {
+		acc.lines++
+	}
+	acc.monetary += t.TotalPrice
+	acc.units += t.Quantity
+	acc.months[monthIndex(t.TransactionDate)] += t.TotalPrice
+}
+
+// merge combines another worker's accumulators into p
+func (p customerPart) merge(o customerPart) {
+	for id, v := range o {
+		acc := p[id]
+		if acc == nil {
+			p[id] = v
+			continue
+		}
+		if v.first.Before(acc.first) {
+			acc.first = v.first
+		}
+		if v.last.After(acc.last) {
+			acc.last = v.last
+		}
+		// An order's lines may be split between workers
+		for k := range v.orders {
+			acc.orders[k] = struct{}{}
+		}
+		acc.lines += v.lines
+		acc.monetary += v.monetary
+		acc.units += v.units
+		for m, rev := range v.months {
+			acc.months[m] += rev
+		}
+	}
+}
+
+// frequency is the number of distinct orders
+func (acc *customerAcc) frequency() int {
+	return len(acc.orders) + acc.lines
+}
+
+// hashKey hashes an ID with 64-bit FNV-1a, so each one costs 8 bytes however
+// long it is
+func hashKey(id string) uint64 {
+	h := uint64(14695981039346656037)
+	for i := 0; i < len(id); i++ {
+		h ^= uint64(id[i])
+		h *= 1099511628211
+	}
+	return h
+}
+
+// quantileScorer assigns 1..bins scores from the position of a value in the
+// sorted population, so equal values always receive the same score.
+type quantileScorer struct {
+	sorted []float64
+	bins   int
+}
+
+// newQuantileScorer sorts values in place and returns a scorer over them
+func newQuantileScorer(values []float64, bins int) quantileScorer {
+	sort.Float64s(values)
+	return quantileScorer{sorted: values, bins: bins}
+}
+
+// score returns 1 for the lowest values and bins for the highest
+func (q quantileScorer) score(v float64) int {
+	below := sort.SearchFloat64s(q.sorted, v)
+	return 1 + below*q.bins/len(q.sorted)
+}
+
+// results scores every customer and summarises revenue by segment. Recency
+// is measured against the latest purchase date in the dataset.
+func (p customerPart) results(opts Options) (map[string]models.CustomerProfile, []models.CustomerSegment) {
+	profiles := make(map[string]models.CustomerProfile, len(p))
+	if len(p) == 0 {
+		return profiles, []models.CustomerSegment{}
+	}
+
+	var ref time.Time
+	for _, acc := range p {
+		if acc.last.After(ref) {
+			ref = acc.last
+		}
+	}
+	recency := func(acc *customerAcc) float64 {
+		return float64(int(ref.Sub(acc.last).Hours() / 24))
+	}
+
+	rs := make([]float64, 0, len(p))
+	fs := make([]float64, 0, len(p))
+	ms := make([]float64, 0, len(p))
+	for _, acc := range p {
+		rs = append(rs, recency(acc))
+		fs = append(fs, float64(acc.frequency()))
+		ms = append(ms, acc.monetary)
+	}
+	rScore := newQuantileScorer(rs, opts.RFMBins)
+	fScore := newQuantileScorer(fs, opts.RFMBins)
+	mScore := newQuantileScorer(ms, opts.RFMBins)
+
+	type segTotals struct {
+		customers int
+		rev       float64
+		recency   float64
+		orders    int
+	}
+	segs := make(map[string]segTotals)
+	var grand float64
+
+	for id, acc := range p {
+		rec := recency(acc)
+		// recent customers get the high recency score
+		r := opts.RFMBins + 1 - rScore.score(rec)
+		f := fScore.score(float64(acc.frequency()))
+		m := mScore.score(acc.monetary)
+
+		seg := otherSegment
+		for _, s := range opts.Segments {
+			if s.matches(r, f, m) {
+				seg = s.Name
+				break
+			}
+		}
+
+		profiles[id] = models.CustomerProfile{
+			UserID:        id,
+			FirstPurchase: acc.first.Format("2006-01-02"),
+			LastPurchase:  acc.last.Format("2006-01-02"),
+			RecencyDays:   int(rec),
+			Frequency:     acc.frequency(),
+			Monetary:      acc.monetary,
+			AvgOrderValue: acc.monetary / float64(acc.frequency()),
+			UnitsBought:   acc.units,
+			RScore:        r,
+			FScore:        f,
+			MScore:        m,
+			Segment:       seg,
+		}
+
+		st := segs[seg]
+		st.customers++
+		st.rev += acc.monetary
+		st.recency += rec
+		st.orders += acc.frequency()
+		segs[seg] = st
+		grand += acc.monetary
+	}
+
+	// Sort segments by revenue (desc), then by name (asc)
+	out := make([]models.CustomerSegment, 0, len(segs))
+	for name, st := range segs {
+		share := 0.0
+		if grand > 0 {
+			share = st.rev / grand
+		}
+		n := float64(st.customers)
+		out = append(out, models.CustomerSegment{
+			Segment:        name,
+			Customers:      st.customers,
+			TotalRevenue:   st.rev,
+			RevenueShare:   share,
+			AvgRecencyDays: st.recency / n,
+			AvgFrequency:   float64(st.orders) / n,
+			AvgMonetary:    st.rev / n,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].TotalRevenue == out[j].TotalRevenue {
+			return out[i].Segment < out[j].Segment
+		}
+		return out[i].TotalRevenue > out[j].TotalRevenue
+	})
+	return profiles, out
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/GimhaniHM/backend/internal/models"
+)
+
+// TestCustomerRFM checks recency/frequency/monetary metrics, scoring and
+// segment assignment for a small population
+func TestCustomerRFM(t *testing.T) {
+	parse := func(s string) time.Time {
+		d, _ := time.Parse("2006-01-02", s)
+		return d
+	}
+	tx := func(user, date string, total float64) models.Transaction {
+		return models.Transaction{UserID: user, TransactionDate: parse(date), TotalPrice: total, Quantity: 1}
+	}
+
+	// split transactions over two partials to exercise merge
+	a, b := make(customerPart), make(customerPart)
+	a.add(tx("U1", "2024-06-01", 100))
+	b.add(tx("U1", "2024-06-30", 100))
+	a.add(tx("U1", "2024-06-15", 100))
+	a.add(tx("U2", "2024-01-01", 10))
+	b.add(tx("U2", "2024-01-05", 10))
+	b.add(tx("U2", "2024-01-09", 10))
+	a.add(tx("U3", "2024-06-29", 5))
+	a.merge(b)
+
+	opts := DefaultOptions()
+	opts.RFMBins = 3
+	opts.Segments = []Segment{
+		{Name: "champions", RMin: 3, RMax: 3, FMin: 2, FMax: 3},
+		{Name: "new_customers", RMin: 2, RMax: 3, FMin: 1, FMax: 1},
+		{Name: "at_risk", RMin: 1, RMax: 1, FMin: 2, FMax: 3},
+	}
+	profiles, segments := a.results(opts)
+
+	u1 := profiles["U1"]
+	if u1.FirstPurchase != "2024-06-01" || u1.LastPurchase != "2024-06-30" ||
+		u1.RecencyDays != 0 || u1.Frequency != 3 || u1.Monetary != 300 || u1.Segment != "champions" {
+		t.Errorf("U1 profile = %+v; want 3 orders, 300 revenue, champions", u1)
+	}
+	if u2 := profiles["U2"]; u2.RecencyDays != 173 || u2.RScore != 1 || u2.Segment != "at_risk" {
+		t.Errorf("U2 profile = %+v; want recency 173, r_score 1, at_risk", u2)
+	}
+	if u3 := profiles["U3"]; u3.Segment != "new_customers" {
+		t.Errorf("U3 profile = %+v; want new_customers", u3)
+	}
+
+	if len(segments) != 3 || segments[0].Segment != "champions" || segments[0].TotalRevenue != 300 {
+		t.Errorf("segments = %+v; want champions first with 300 revenue", segments)
+	}
+}
+
+// TestCustomerFrequencyCountsOrders checks frequency counts distinct
+// transaction IDs, even when an order's lines reach different workers
+func TestCustomerFrequencyCountsOrders(t *testing.T) {
+	day, _ := time.Parse("2006-01-02", "2024-06-01")
+	line := func(id string, total float64) models.Transaction {
+		return models.Transaction{TransactionID: id, UserID: "U1", TransactionDate: day, TotalPrice: total, Quantity: 1}
+	}
+	a, b := make(customerPart), make(customerPart)
+	a.add(line("O1", 10))
+	b.add(line("O1", 20))
+	a.add(line("O2", 30))
+	a.merge(b)
+
+	profiles, _ := a.results(DefaultOptions())
+	if p := profiles["U1"]; p.Frequency != 2 || p.AvgOrderValue != 30 {
+		t.Errorf("U1 profile = %+v; want 2 orders averaging 30", p)
+	}
+}
+
+// TestCohorts checks cohort sizes, retention percentages and revenue by
+// month offset
+func TestCohorts(t *testing.T) {
+	parse := func(s string) time.Time {
+		d, _ := time.Parse("2006-01-02", s)
+		return d
+	}
+	p := make(customerPart)
+	for _, tx := range []models.Transaction{
+		{UserID: "U1", TransactionDate: parse("2024-01-03"), TotalPrice: 10},
+		{UserID: "U1", TransactionDate: parse("2024-03-03"), TotalPrice: 20},
+		{UserID: "U2", TransactionDate: parse("2024-01-09"), TotalPrice: 5},
+		{UserID: "U2", TransactionDate: parse("2024-02-09"), TotalPrice: 5},
+		{UserID: "U3", TransactionDate: parse("2024-02-01"), TotalPrice: 7},
+		{UserID: "U4", TransactionDate: parse("bad-date"), TotalPrice: 9}, // no cohort
+	} {
+		p.add(tx)
+	}
+
+	got := p.cohorts()
+	want := []models.Cohort{
+		{Cohort: "2024-01", Customers: 2, Active: []int{2, 1, 1}, Retention: []float64{100, 50, 50}, Revenue: []float64{15, 5, 20}},
+		{Cohort: "2024-02", Customers: 1, Active: []int{1, 0}, Retention: []float64{100, 0}, Revenue: []float64{7, 0}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("cohorts() = %+v; want %+v", got, want)
+	}
+}
@@ -0,0 +1,46 @@
+package services
+
+import "github.com/GimhaniHM/backend/internal/sketch"
+
+// newDistinct creates an empty distinct counter according to the options
+func (o Options) newDistinct() sketch.DistinctCounter {
+	if o.ExactDistinct {
+		return sketch.NewExactCounter()
+	}
+	return sketch.NewHyperLogLog(o.DistinctPrecision)
+}
+
+// distinctGroups keeps one distinct counter per group key
+type distinctGroups map[string]sketch.DistinctCounter
+
+// add records a user under the given group, skipping blank user IDs
+func (d distinctGroups) add(opts Options, group, user string) {
+	if user == "" {
+		return
+	}
+	dc := d[group]
+	if dc == nil {
+		dc = opts.newDistinct()
+		d[group] = dc
+	}
+	dc.Add(user)
+}
+
+// merge folds another worker's counters into d
+func (d distinctGroups) merge(o distinctGroups) {
+	for k, v := range o {
+		if dc := d[k]; dc != nil {
+			dc.Merge(v)
+		} else {
+			d[k] = v
+		}
+	}
+}
+
+// count returns the distinct count for a group (0 if unseen)
+func (d distinctGroups) count(group string) int {
+	if dc := d[group]; dc != nil {
+		return int(dc.Count())
+	}
+	return 0
+}
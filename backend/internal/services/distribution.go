@@ -0,0 +1,138 @@
+package services
+
+import (
+	"math"
+	"sort"
+
+	"github.com/GimhaniHM/backend/internal/models"
+	"github.com/GimhaniHM/backend/internal/sketch"
+)
+
+// Distribution dimensions and metrics served by DistributionIndex
+var (
+	DistributionDimensions = []string{"total", "country", "region", "month"}
+	DistributionMetrics    = []string{"order_value", "quantity"}
+)
+
+// order value and quantity digests for one group
+type distPair struct {
+	value *sketch.TDigest
+	qty   *sketch.TDigest
+}
+
+// distPart holds one worker's digests, keyed by dimension then group
+type distPart map[string]map[string]*distPair
+
+// creates an empty distPart with one map per dimension
+func newDistPart() distPart {
+	p := make(distPart, len(DistributionDimensions))
+	for _, dim := range DistributionDimensions {
+		p[dim] = make(map[string]*distPair)
+	}
+	return p
+}
+
+// add records a transaction's order value and quantity in every dimension
+func (p distPart) add(opts Options, t models.Transaction, month string) {
+	keys := [...]struct{ dim, key string }{
+		{"total", "all"},
+		{"country", t.Country},
+		{"region", t.Region},
+		{"month", month},
+	}
+	for _, k := range keys {
+		dp := p[k.dim][k.key]
+		if dp == nil {
+			dp = &distPair{
+				value: sketch.NewTDigest(opts.DigestCompression),
+				qty:   sketch.NewTDigest(opts.DigestCompression),
+			}
+			p[k.dim][k.key] = dp
+		}
+		dp.value.Add(t.TotalPrice)
+		dp.qty.Add(float64(t.Quantity))
+	}
+}
+
+// merge folds another worker's digests into p
+func (p distPart) merge(o distPart) {
+	for dim, groups := range o {
+		for k, v := range groups {
+			if dp := p[dim][k]; dp != nil {
+				dp.value.Merge(v.value)
+				dp.qty.Merge(v.qty)
+			} else {
+				p[dim][k] = v
+			}
+		}
+	}
+}
+
+// DistributionIndex answers distribution queries from merged digests
+type DistributionIndex struct {
+	groups distPart
+}
+
+// Distributions summarises one metric for every group of a dimension, with
+// an equal-width histogram of the given number of bins. ok is false for an
+// unknown dimension or metric.
+func (d *DistributionIndex) Distributions(dimension, metric string, bins int) (out []models.Distribution, ok bool) {
+	if d == nil {
+		return nil, false
+	}
+	groups, ok := d.groups[dimension]
+	if !ok || (metric != "order_value" && metric != "quantity") {
+		return nil, false
+	}
+
+	out = make([]models.Distribution, 0, len(groups))
+	for key, dp := range groups {
+		td := dp.value
+		if metric == "quantity" {
+			td = dp.qty
+		}
+		out = append(out, models.Distribution{
+			Key:       key,
+			Count:     int(td.Count()),
+			Mean:      td.Mean(),
+			Min:       td.Min(),
+			Max:       td.Max(),
+			P50:       td.Quantile(0.5),
+			P90:       td.Quantile(0.9),
+			P99:       td.Quantile(0.99),
+			Histogram: histogram(td, bins),
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out, true
+}
+
+// histogram splits [min, max] into equal-width bins and estimates each
+// bin's count from the digest's CDF
+func histogram(td *sketch.TDigest, bins int) []models.HistogramBin {
+	lo, hi := td.Min(), td.Max()
+	if td.Count() == 0 {
+		return []models.HistogramBin{}
+	}
+	if hi == lo {
+		return []models.HistogramBin{{Lower: lo, Upper: hi, Count: int(td.Count())}}
+	}
+
+	width := (hi - lo) / float64(bins)
+	out := make([]models.HistogramBin, bins)
+	prev := 0.0
+	for i := range out {
+		upper := lo + width*float64(i+1)
+		cdf := 1.0
+		if i < bins-1 {
+			cdf = td.CDF(upper)
+		}
+		out[i] = models.HistogramBin{
+			Lower: lo + width*float64(i),
+			Upper: upper,
+			Count: int(math.Round((cdf - prev) * td.Count())),
+		}
+		prev = cdf
+	}
+	return out
+}
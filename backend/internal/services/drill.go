@@ -0,0 +1,148 @@
+package services
+
+import (
+	"sort"
+
+	"github.com/GimhaniHM/backend/internal/models"
+)
+
+// DrillIndex serves country and region drill-downs, built once from the
+// rollup leaves, distinct customer counts and monthly series
+type DrillIndex struct {
+	countries map[string]*models.Drilldown
+	regions   map[string]*models.Drilldown
+}
+
+// running totals and child breakdowns for one drill-down node
+type drillAcc struct {
+	tot      categoryTotals
+	children map[string]categoryTotals // regions of a country, countries of a region
+	products map[string]categoryTotals
+}
+
+// buildDrillIndex groups the country/region/product leaves under each
+// country and each region and renders the drill-downs. Leaves without a
+// product ID are left out of the product breakdowns.
+func buildDrillIndex(leaves map[cubeKey]categoryTotals, products productPart, series *SeriesIndex, countryUsers, regionUsers distinctGroups) *DrillIndex {
+	countries := make(map[string]*drillAcc)
+	regions := make(map[string]*drillAcc)
+	get := func(m map[string]*drillAcc, k string) *drillAcc {
+		acc := m[k]
+		if acc == nil {
+			acc = &drillAcc{children: make(map[string]categoryTotals), products: make(map[string]categoryTotals)}
+			m[k] = acc
+		}
+		return acc
+	}
+	add := func(m map[string]categoryTotals, k string, v categoryTotals) {
+		ct := m[k]
+		ct.rev += v.rev
+		ct.units += v.units
+		ct.cnt += v.cnt
+		m[k] = ct
+	}
+	for k, v := range leaves {
+		country, region, product := k[0], k[1], k[2]
+		for _, node := range []struct {
+			acc   *drillAcc
+			child string
+		}{{get(countries, country), region}, {get(regions, region), country}} {
+			node.acc.tot.rev += v.rev
+			node.acc.tot.units += v.units
+			node.acc.tot.cnt += v.cnt
+			add(node.acc.children, node.child, v)
+			if product != "" {
+				add(node.acc.products, product, v)
+			}
+		}
+	}
+
+	d := &DrillIndex{
+		countries: make(map[string]*models.Drilldown, len(countries)),
+		regions:   make(map[string]*models.Drilldown, len(regions)),
+	}
+	for name, acc := range countries {
+		dd := acc.render(name, "country", countryUsers.count(name), products, series)
+		dd.Regions = drillChildren(acc.children, acc.tot.rev)
+		d.countries[name] = dd
+	}
+	for name, acc := range regions {
+		dd := acc.render(name, "region", regionUsers.count(name), products, series)
+		dd.Countries = drillChildren(acc.children, acc.tot.rev)
+		d.regions[name] = dd
+	}
+	return d
+}
+
+// render builds the KPIs, product breakdown and months of a node. Products
+// are keyed by ID and labelled with their latest name.
+func (acc *drillAcc) render(name, level string, customers int, products productPart, series *SeriesIndex) *models.Drilldown {
+	dd := &models.Drilldown{
+		Name:             name,
+		Level:            level,
+		TotalRevenue:     acc.tot.rev,
+		UnitsSold:        acc.tot.units,
+		TransactionCount: acc.tot.cnt,
+		UniqueCustomers:  customers,
+		ProductCount:     len(acc.products),
+		Products:         drillChildren(acc.products, acc.tot.rev),
+		Months:           []models.MonthTotal{},
+	}
+	for i := range dd.Products {
+		p := &dd.Products[i]
+		p.ID = p.Name
+		if pa := products[p.ID]; pa != nil {
+			p.Name = pa.name
+		}
+	}
+	if acc.tot.cnt > 0 {
+		dd.AvgOrderValue = acc.tot.rev / float64(acc.tot.cnt)
+	}
+	labels, rev, _ := series.Series(level, name, GranularityMonth, MetricRevenue)
+	_, units, _ := series.Series(level, name, GranularityMonth, MetricUnits)
+	for i, m := range labels {
+		dd.Months = append(dd.Months, models.MonthTotal{Month: m, TotalRevenue: rev[i], UnitsSold: int(units[i])})
+	}
+	return dd
+}
+
+// drillChildren sorts a breakdown by revenue (desc), then name (asc)
+func drillChildren(m map[string]categoryTotals, parentRev float64) []models.Breakdown {
+	out := make([]models.Breakdown, 0, len(m))
+	for k, v := range m {
+		row := models.Breakdown{Name: k, TotalRevenue: v.rev, UnitsSold: v.units, TransactionCount: v.cnt}
+		if parentRev > 0 {
+			row.RevenueShare = v.rev / parentRev
+		}
+		out = append(out, row)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].TotalRevenue != out[j].TotalRevenue {
+			return out[i].TotalRevenue > out[j].TotalRevenue
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+// Country returns the drill-down of one country with at most limit products
+func (d *DrillIndex) Country(name string, limit int) (models.Drilldown, bool) {
+	return drillPage(d.countries[name], limit)
+}
+
+// Region returns the drill-down of one region with at most limit products
+func (d *DrillIndex) Region(name string, limit int) (models.Drilldown, bool) {
+	return drillPage(d.regions[name], limit)
+}
+
+// drillPage copies a drill-down with its product list truncated
+func drillPage(dd *models.Drilldown, limit int) (models.Drilldown, bool) {
+	if dd == nil {
+		return models.Drilldown{}, false
+	}
+	out := *dd
+	if len(out.Products) > limit {
+		out.Products = out.Products[:limit]
+	}
+	return out, true
+}
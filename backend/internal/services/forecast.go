@@ -0,0 +1,220 @@
+package services
+
+import (
+	"math"
+	"time"
+
+	"github.com/GimhaniHM/backend/internal/models"
+)
+
+// Forecast models, from most to least history required
+const (
+	ModelHoltWinters = "holt_winters" // additive trend and 12-month season
+	ModelHolt        = "holt"         // additive trend
+	ModelMean        = "mean"
+)
+
+// seasonLength is the number of months in a seasonal cycle
+const seasonLength = 12
+
+// ForecastQuery selects the monthly series to forecast
+type ForecastQuery struct {
+	Dimension string  // one of SeriesDimensions
+	Key       string  // member of Dimension ("all" for total)
+	Metric    string  // MetricRevenue or MetricUnits
+	Horizon   int     // number of months ahead
+	Level     float64 // confidence level in percent, e.g. 95
+}
+
+// Forecast fits an exponential smoothing model to a monthly series and
+// projects it Horizon months ahead. Holt-Winters is used once two full
+// seasons are available, Holt's linear trend with at least three months
+// and the mean otherwise; smoothing parameters minimise the one-step-ahead
+// squared error. Intervals assume the one-step error grows with sqrt(h).
+// The backtest refits on all but the last min(Horizon, n/3) months and
+// scores the forecast of those months. ok is false for an unknown series.
+func (s *SeriesIndex) Forecast(q ForecastQuery) (models.Forecast, bool) {
+	labels, values, ok := s.Series(q.Dimension, q.Key, GranularityMonth, q.Metric)
+	if !ok || len(values) == 0 {
+		return models.Forecast{}, false
+	}
+
+	fit := fitSmoother(values)
+	out := models.Forecast{
+		Dimension: q.Dimension,
+		Key:       q.Key,
+		Metric:    q.Metric,
+		Model:     fit.model,
+		Alpha:     fit.alpha,
+		Beta:      fit.beta,
+		Gamma:     fit.gamma,
+		Level:     q.Level,
+		History:   make([]models.SeriesPoint, len(values)),
+		Forecast:  make([]models.ForecastPoint, q.Horizon),
+	}
+	for i, v := range values {
+		out.History[i] = models.SeriesPoint{Period: labels[i], Value: v}
+	}
+
+	// Confidence interval half-width per step, from the normal quantile
+	z := math.Sqrt2 * math.Erfinv(q.Level/100)
+	last, _ := time.Parse("2006-01", labels[len(labels)-1])
+	next := monthIndex(last) + 1
+	for h, v := range fit.predict(q.Horizon) {
+		width := z * fit.sigma * math.Sqrt(float64(h+1))
+		out.Forecast[h] = models.ForecastPoint{
+			Period: monthLabel(next + h),
+			Value:  math.Max(v, 0),
+			Lower:  math.Max(v-width, 0),
+			Upper:  math.Max(v+width, 0),
+		}
+	}
+
+	if hold := min(q.Horizon, len(values)/3); hold > 0 {
+		out.Backtest = backtest(values, hold)
+	}
+	return out, true
+}
+
+// smoother is a fitted exponential smoothing model and its final state
+type smoother struct {
+	model              string
+	alpha, beta, gamma float64
+
+	level, trend float64
+	seasonal     []float64 // indexed by period mod seasonLength
+	n            int       // number of periods fitted
+	sigma        float64   // RMS one-step-ahead error
+}
+
+// fitSmoother picks the model for the length of y and grid searches its
+// smoothing parameters
+func fitSmoother(y []float64) smoother {
+	season := 0
+	switch {
+	case len(y) >= 2*seasonLength:
+		season = seasonLength
+	case len(y) < 3:
+		return meanModel(y)
+	}
+
+	grid := []float64{0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7, 0.8, 0.9}
+	gammas := []float64{0}
+	if season > 0 {
+		gammas = grid
+	}
+	var best smoother
+	bestSSE := math.Inf(1)
+	for _, a := range grid {
+		for _, b := range grid {
+			for _, g := range gammas {
+				sm, sse := runSmoother(y, a, b, g, season)
+				if sse < bestSSE {
+					best, bestSSE = sm, sse
+				}
+			}
+		}
+	}
+	return best
+}
+
+// runSmoother applies additive Holt(-Winters) smoothing to y and returns
+// the final state along with the sum of squared one-step-ahead errors.
+// With a season the first two cycles initialise level, trend and seasonal
+// indices; without one the first two points set level and
+// trend.
+func runSmoother(y []float64, alpha, beta, gamma float64, season int) (smoother, float64) {
+	sm := smoother{model: ModelHolt, alpha: alpha, beta: beta, n: len(y)}
+	start := 1
+	sm.level, sm.trend = y[0], y[1]-y[0]
+	if season > 0 {
+		sm.model, sm.gamma = ModelHoltWinters, gamma
+		first, second := meanOf(y[:season]), meanOf(y[season:2*season])
+		sm.trend = (second - first) / float64(season)
+		// the first cycle's mean sits at its midpoint; detrend around it
+		mid := float64(season-1) / 2
+		sm.level = first + mid*sm.trend
+		sm.seasonal = make([]float64, season)
+		for i := range sm.seasonal {
+			sm.seasonal[i] = y[i] - (first + (float64(i)-mid)*sm.trend)
+		}
+		start = season
+	}
+
+	var sse float64
+	for t := start; t < len(y); t++ {
+		var s float64
+		if season > 0 {
+			s = sm.seasonal[t%season]
+		}
+		e := y[t] - (sm.level + sm.trend + s)
+		sse += e * e
+
+		prev := sm.level
+		sm.level = alpha*(y[t]-s) + (1-alpha)*(sm.level+sm.trend)
+		sm.trend = beta*(sm.level-prev) + (1-beta)*sm.trend
+		if season > 0 {
+			sm.seasonal[t%season] = gamma*(y[t]-sm.level) + (1-gamma)*s
+		}
+	}
+	sm.sigma = math.Sqrt(sse / float64(len(y)-start))
+	return sm, sse
+}
+
+// meanModel forecasts the mean, with the standard deviation as error
+func meanModel(y []float64) smoother {
+	m := meanOf(y)
+	var ss float64
+	for _, v := range y {
+		ss += (v - m) * (v - m)
+	}
+	return smoother{model: ModelMean, level: m, n: len(y), sigma: math.Sqrt(ss / float64(len(y)))}
+}
+
+// predict projects the fitted model h periods past the end of the series
+func (sm smoother) predict(h int) []float64 {
+	out := make([]float64, h)
+	for i := range out {
+		out[i] = sm.level + float64(i+1)*sm.trend
+		if len(sm.seasonal) > 0 {
+			out[i] += sm.seasonal[(sm.n+i)%len(sm.seasonal)]
+		}
+	}
+	return out
+}
+
+// backtest fits on y without its last hold points and scores the forecast
+// of those points
+func backtest(y []float64, hold int) *models.Backtest {
+	train, test := y[:len(y)-hold], y[len(y)-hold:]
+	pred := fitSmoother(train).predict(hold)
+
+	bt := &models.Backtest{Periods: hold}
+	var se, ape float64
+	nonzero := 0
+	for i, actual := range test {
+		e := actual - math.Max(pred[i], 0)
+		bt.MAE += math.Abs(e)
+		se += e * e
+		if actual != 0 {
+			ape += math.Abs(e / actual)
+			nonzero++
+		}
+	}
+	bt.MAE /= float64(hold)
+	bt.RMSE = math.Sqrt(se / float64(hold))
+	if nonzero > 0 {
+		mape := ape / float64(nonzero) * 100
+		bt.MAPE = &mape
+	}
+	return bt
+}
+
+// meanOf returns the arithmetic mean of a non-empty slice
+func meanOf(y []float64) float64 {
+	var sum float64
+	for _, v := range y {
+		sum += v
+	}
+	return sum / float64(len(y))
+}
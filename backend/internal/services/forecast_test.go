@@ -0,0 +1,60 @@
+package services
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/GimhaniHM/backend/internal/models"
+)
+
+// TestForecast checks model selection by history length and that a clean
+// trend plus season is projected and backtested accurately
+func TestForecast(t *testing.T) {
+	start, _ := time.Parse("2006-01-02", "2022-01-01")
+	// 36 months of trend plus a December peak
+	monthly := func(m int) float64 {
+		v := 1000 + 10*float64(m)
+		if m%12 == 11 {
+			v += 300
+		}
+		return v
+	}
+	series := make(seriesPart)
+	for m := 0; m < 36; m++ {
+		series.add(models.Transaction{
+			Country: "USA", Region: "West", Category: "Toys",
+			TransactionDate: start.AddDate(0, m, 0), Quantity: 1, TotalPrice: monthly(m),
+		})
+	}
+	idx := &SeriesIndex{days: series}
+
+	f, ok := idx.Forecast(ForecastQuery{Dimension: "total", Key: "all", Metric: MetricRevenue, Horizon: 12, Level: 95})
+	if !ok || f.Model != ModelHoltWinters || len(f.History) != 36 || len(f.Forecast) != 12 {
+		t.Fatalf("Forecast = %s model, %d history, %d forecast, ok %v", f.Model, len(f.History), len(f.Forecast), ok)
+	}
+	if f.Forecast[0].Period != "2025-01" {
+		t.Errorf("first forecast period = %s; want 2025-01", f.Forecast[0].Period)
+	}
+	for h, p := range f.Forecast {
+		want := monthly(36 + h)
+		if math.Abs(p.Value-want) > 0.05*want || p.Lower > p.Value || p.Upper < p.Value {
+			t.Errorf("forecast %s = %+v; want ~%.0f inside its interval", p.Period, p, want)
+		}
+	}
+	if f.Backtest == nil || f.Backtest.Periods != 12 || f.Backtest.MAPE == nil || *f.Backtest.MAPE > 5 {
+		t.Errorf("backtest = %+v; want 12 periods under 5%% MAPE", f.Backtest)
+	}
+
+	// Too little history for a season falls back to a trend, then the mean
+	short := make(seriesPart)
+	for m := 0; m < 2; m++ {
+		short.add(models.Transaction{Country: "UK", TransactionDate: start.AddDate(0, m, 0), TotalPrice: 100})
+	}
+	if f, _ := (&SeriesIndex{days: short}).Forecast(ForecastQuery{Dimension: "country", Key: "UK", Metric: MetricRevenue, Horizon: 3, Level: 95}); f.Model != ModelMean || f.Forecast[2].Value != 100 || f.Backtest != nil {
+		t.Errorf("short forecast = %+v; want flat mean without backtest", f)
+	}
+	if _, ok := idx.Forecast(ForecastQuery{Dimension: "country", Key: "UK", Metric: MetricRevenue, Horizon: 3, Level: 95}); ok {
+		t.Errorf("unknown series accepted")
+	}
+}
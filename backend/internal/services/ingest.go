@@ -0,0 +1,61 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/GimhaniHM/backend/internal/models"
+)
+
+// ingestReport summarises what was read and flags product names shared by
+// several IDs and IDs seen under several names. Outputs are keyed by
+// product ID, so collisions are kept apart and renames use the latest name.
+// skipped counts rows the CSV reader rejected and invalid rows whose date or
+// numbers did not parse; both are left out of every insight.
+func (p productPart) ingestReport(read, skipped, invalid int) models.IngestReport {
+	r := models.IngestReport{
+		RowsRead:        read,
+		RowsSkipped:     skipped + invalid,
+		Products:        len(p),
+		NameCollisions:  []models.NameCollision{},
+		RenamedProducts: []models.RenamedProduct{},
+		Warnings:        []string{},
+	}
+
+	byName := make(map[string][]string)
+	for id, acc := range p {
+		for n := range acc.names {
+			byName[n] = append(byName[n], id)
+		}
+		if len(acc.names) > 1 {
+			names := make([]string, 0, len(acc.names))
+			for n := range acc.names {
+				names = append(names, n)
+			}
+			sort.Strings(names)
+			r.RenamedProducts = append(r.RenamedProducts, models.RenamedProduct{ProductID: id, CurrentName: acc.name, Names: names})
+		}
+	}
+	for n, ids := range byName {
+		if len(ids) > 1 {
+			sort.Strings(ids)
+			r.NameCollisions = append(r.NameCollisions, models.NameCollision{ProductName: n, ProductIDs: ids})
+		}
+	}
+	sort.Slice(r.NameCollisions, func(i, j int) bool { return r.NameCollisions[i].ProductName < r.NameCollisions[j].ProductName })
+	sort.Slice(r.RenamedProducts, func(i, j int) bool { return r.RenamedProducts[i].ProductID < r.RenamedProducts[j].ProductID })
+
+	if skipped > 0 {
+		r.Warnings = append(r.Warnings, fmt.Sprintf("%d malformed rows were skipped", skipped))
+	}
+	if invalid > 0 {
+		r.Warnings = append(r.Warnings, fmt.Sprintf("%d rows with an unparseable date or number were skipped", invalid))
+	}
+	if n := len(r.NameCollisions); n > 0 {
+		r.Warnings = append(r.Warnings, fmt.Sprintf("%d product names are shared by more than one product ID; outputs keep them apart by product_id", n))
+	}
+	if n := len(r.RenamedProducts); n > 0 {
+		r.Warnings = append(r.Warnings, fmt.Sprintf("%d product IDs appear under more than one name; outputs use the latest name", n))
+	}
+	return r
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"sort"
+	"time"
+
+	"github.com/GimhaniHM/backend/internal/models"
+)
+
+// Inventory status values
+const (
+	StockOut  = "out_of_stock"
+	StockLow  = "low_stock"
+	StockOver = "overstock"
+	StockOK   = "ok"
+)
+
+// inventory derives current stock, sales velocity and days of cover for
+// every product, plus each product's monthly stock series. Velocity is the
+// units sold over the last opts.VelocityMonths calendar months of data
+// divided by the days in that window.
+func (p productPart) inventory(opts Options) ([]models.InventoryStatus, map[string][]models.StockPoint) {
+	series := make(map[string][]models.StockPoint, len(p))
+	out := make([]models.InventoryStatus, 0, len(p))
+	if len(p) == 0 {
+		return out, series
+	}
+
+	// The velocity window ends on the last transaction date in the data
+	last := p.lastDate()
+	lastMonth := monthIndex(last)
+	firstMonth := lastMonth - opts.VelocityMonths + 1
+	windowStart := last.AddDate(0, 0, 1-last.Day()).AddDate(0, 1-opts.VelocityMonths, 0)
+	windowDays := float64(int(last.Sub(windowStart).Hours()/24) + 1)
+
+	for id, acc := range p {
+		recent := 0
+		points := make([]models.StockPoint, 0, len(acc.months))
+		for mi, pm := range acc.months {
+			if mi >= firstMonth {
+				recent += pm.units
+			}
+			points = append(points, models.StockPoint{
+				Month:     monthLabel(mi),
+				Stock:     pm.stock.qty,
+				AsOf:      pm.stock.at.Format("2006-01-02"),
+				UnitsSold: pm.units,
+			})
+		}
+		sort.Slice(points, func(i, j int) bool { return points[i].Month < points[j].Month })
+		series[id] = points
+
+		st := models.InventoryStatus{
+			ProductID:     id,
+			ProductName:   acc.name,
+			Category:      acc.category,
+			CurrentStock:  acc.stock.qty,
+			StockAsOf:     acc.stock.at.Format("2006-01-02"),
+			DailyVelocity: float64(recent) / windowDays,
+		}
+		if st.DailyVelocity > 0 {
+			cover := float64(st.CurrentStock) / st.DailyVelocity
+			st.DaysOfCover = &cover
+		}
+		st.Status = stockStatus(st, opts)
+		out = append(out, st)
+	}
+
+	// Sort by days of cover (asc, products without sales last), then by ID
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i].DaysOfCover, out[j].DaysOfCover
+		if (a == nil) != (b == nil) {
+			return b == nil
+		}
+		if a != nil && *a != *b {
+			return *a < *b
+		}
+		return out[i].ProductID < out[j].ProductID
+	})
+	return out, series
+}
+
+// lastDate returns the latest transaction date seen for any product
+func (p productPart) lastDate() (last time.Time) {
+	for _, acc := range p {
+		if acc.stock.at.After(last) {
+			last = acc.stock.at
+		}
+	}
+	return last
+}
+
+// stockStatus flags a product as out of stock, low, overstocked or ok
+func stockStatus(st models.InventoryStatus, opts Options) string {
+	switch {
+	case st.CurrentStock <= 0:
+		return StockOut
+	case st.DaysOfCover == nil:
+		// stock on hand but nothing sold recently
+		return StockOver
+	case *st.DaysOfCover < float64(opts.LowStockDays):
+		return StockLow
+	case *st.DaysOfCover > float64(opts.OverstockDays):
+		return StockOver
+	}
+	return StockOK
+}
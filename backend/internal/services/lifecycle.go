@@ -0,0 +1,85 @@
+package services
+
+import (
+	"sort"
+	"time"
+
+	"github.com/GimhaniHM/backend/internal/models"
+)
+
+// lifecycle reports each product's age, time to first sale and early sales,
+// and groups launches by the month of their added_date. Products without a
+// valid added_date are left out.
+func (p productPart) lifecycle() ([]models.ProductLifecycle, []models.LaunchCohort) {
+	last := p.lastDate()
+	days := func(from, to time.Time) int { return int(to.Sub(from).Hours() / 24) }
+
+	type launchTotals struct {
+		models.LaunchCohort
+		daysToFirst int
+	}
+	launches := make(map[string]*launchTotals)
+
+	out := make([]models.ProductLifecycle, 0, len(p))
+	for id, acc := range p {
+		if acc.added.IsZero() {
+			continue
+		}
+		units30, rev30 := acc.window(30)
+		units90, rev90 := acc.window(launchWindow)
+		row := models.ProductLifecycle{
+			ProductID:       id,
+			ProductName:     acc.name,
+			Category:        acc.category,
+			AddedDate:       acc.added.Format("2006-01-02"),
+			AgeDays:         days(acc.added, last),
+			FirstSale:       acc.firstSale.Format("2006-01-02"),
+			DaysToFirstSale: days(acc.added, acc.firstSale),
+			UnitsFirst30:    units30,
+			UnitsFirst90:    units90,
+			RevenueFirst30:  rev30,
+			RevenueFirst90:  rev90,
+			TotalUnits:      acc.units,
+			TotalRevenue:    acc.revenue,
+		}
+		out = append(out, row)
+
+		month := acc.added.Format("2006-01")
+		lt := launches[month]
+		if lt == nil {
+			lt = &launchTotals{LaunchCohort: models.LaunchCohort{LaunchMonth: month}}
+			launches[month] = lt
+		}
+		lt.Products++
+		lt.daysToFirst += row.DaysToFirstSale
+		lt.UnitsFirst30 += row.UnitsFirst30
+		lt.UnitsFirst90 += row.UnitsFirst90
+		lt.RevenueFirst30 += row.RevenueFirst30
+		lt.RevenueFirst90 += row.RevenueFirst90
+		lt.TotalRevenue += row.TotalRevenue
+		if row.AgeDays < 90 {
+			lt.PartialWindow = true
+		}
+	}
+
+	// Sort products newest launch first, then by ID
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].AddedDate != out[j].AddedDate {
+			return out[i].AddedDate > out[j].AddedDate
+		}
+		return out[i].ProductID < out[j].ProductID
+	})
+
+	// Average per launch month and sort chronologically
+	cohorts := make([]models.LaunchCohort, 0, len(launches))
+	for _, lt := range launches {
+		n := float64(lt.Products)
+		lt.AvgDaysToFirstSale = float64(lt.daysToFirst) / n
+		lt.AvgUnitsFirst30 = float64(lt.UnitsFirst30) / n
+		lt.AvgUnitsFirst90 = float64(lt.UnitsFirst90) / n
+		cohorts = append(cohorts, lt.LaunchCohort)
+	}
+	sort.Slice(cohorts, func(i, j int) bool { return cohorts[i].LaunchMonth < cohorts[j].LaunchMonth })
+
+	return out, cohorts
+}
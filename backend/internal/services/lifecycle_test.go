@@ -0,0 +1,59 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/GimhaniHM/backend/internal/models"
+)
+
+// TestLifecycle checks time to first sale, 30/90-day windows and launch
+// month grouping
+func TestLifecycle(t *testing.T) {
+	parse := func(s string) time.Time {
+		d, _ := time.Parse("2006-01-02", s)
+		return d
+	}
+	tx := func(id, date, added string, qty int) models.Transaction {
+		return models.Transaction{
+			ProductID: id, ProductName: "Name" + id, Category: "Toys",
+			TransactionDate: parse(date), AddedDate: parse(added),
+			Quantity: qty, TotalPrice: float64(qty),
+		}
+	}
+
+	a, b := make(productPart), make(productPart)
+	a.add(tx("P1", "2024-01-11", "2024-01-01", 1)) // day 10
+	b.add(tx("P1", "2024-02-15", "2024-01-01", 2)) // day 45
+	a.add(tx("P1", "2024-06-01", "2024-01-01", 4)) // day 152
+	b.add(tx("P2", "2024-01-25", "2024-01-20", 3)) // day 5
+	a.add(tx("P3", "2024-05-31", "2024-05-01", 1)) // day 30, outside 30d window
+	// A later added_date on P2 is measured from the earliest one (2024-01-20)
+	a.add(tx("P2", "2024-02-22", "2024-02-20", 5)) // day 33, outside 30d window
+	a.merge(b)
+
+	products, launches := a.lifecycle()
+
+	wantP1 := models.ProductLifecycle{
+		ProductID: "P1", ProductName: "NameP1", Category: "Toys",
+		AddedDate: "2024-01-01", AgeDays: 152, FirstSale: "2024-01-11", DaysToFirstSale: 10,
+		UnitsFirst30: 1, UnitsFirst90: 3, RevenueFirst30: 1, RevenueFirst90: 3,
+		TotalUnits: 7, TotalRevenue: 7,
+	}
+	if len(products) != 3 || products[0].ProductID != "P3" || !reflect.DeepEqual(products[2], wantP1) {
+		t.Errorf("lifecycle products = %+v; want P3 first and P1 = %+v", products, wantP1)
+	}
+
+	if len(launches) != 2 {
+		t.Fatalf("launches = %+v; want 2 months", launches)
+	}
+	jan := launches[0]
+	if jan.LaunchMonth != "2024-01" || jan.Products != 2 || jan.AvgDaysToFirstSale != 7.5 ||
+		jan.UnitsFirst30 != 4 || jan.AvgUnitsFirst90 != 5.5 || jan.PartialWindow {
+		t.Errorf("launches[0] = %+v; want 2024-01 with 2 products", jan)
+	}
+	if may := launches[1]; may.LaunchMonth != "2024-05" || may.UnitsFirst30 != 0 || !may.PartialWindow {
+		t.Errorf("launches[1] = %+v; want 2024-05 with a partial window", may)
+	}
+}
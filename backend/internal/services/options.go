@@ -0,0 +1,111 @@
+package services
+
+import "github.com/GimhaniHM/backend/internal/utils"
+
+// Options tunes how ConcurrentAggregator computes its insights
+type Options struct {
+	// ExactDistinct counts unique customers with exact sets instead of
+	// HyperLogLog sketches. Memory grows with the number of users, so it
+	// is only suitable for small datasets.
+	ExactDistinct bool
+
+	// DistinctPrecision is the HyperLogLog precision (4-18). Each sketch
+	// uses 2^precision bytes; the standard error is ~1.04/sqrt(2^precision).
+	DistinctPrecision uint8
+
+	// RFMBins is the number of score buckets used for recency, frequency
+	// and monetary scores (5 gives the usual 1-5 scale).
+	RFMBins int
+
+	// Segments are the RFM segment rules, evaluated in order
+	Segments []Segment
+
+	// BasketMaxItems skips pair counting for baskets with more distinct
+	// products than this, since pairs grow quadratically with basket size.
+	BasketMaxItems int
+
+	// BasketMaxPairs caps the number of product pairs tracked while mining,
+	// across all workers. Rare pairs are dropped once the cap is reached.
+	BasketMaxPairs int
+
+	// BasketBufferRows is the number of purchase lines each worker holds
+	// (16 bytes each) before spilling them to temporary files for basket
+	// mining.
+	BasketBufferRows int
+
+	// BasketMinCount is the minimum number of shared baskets for a pair to
+	// be reported.
+	BasketMinCount int
+
+	// VelocityMonths is the number of most recent calendar months used to
+	// estimate each product's daily sales velocity.
+	VelocityMonths int
+
+	// LowStockDays flags products with fewer days of cover than this
+	LowStockDays int
+
+	// OverstockDays flags products with more days of cover than this
+	OverstockDays int
+
+	// ParetoA and ParetoB are the default ABC tier thresholds in percent of
+	// revenue (tier C receives the remainder), e.g. 80 and 15.
+	ParetoA, ParetoB float64
+
+	// PriceBands are the ascending lower bounds of the unit price bands used
+	// for price histograms; the last band is open-ended.
+	PriceBands []float64
+
+	// PriceChangePct is the minimum month-over-month change in a product's
+	// average selling price, in percent, reported as a price change.
+	PriceChangePct float64
+
+	// DigestCompression is the t-digest compression used for order value
+	// and quantity distributions; higher is more accurate but larger.
+	DigestCompression float64
+
+	// TopKCapacity ranks top products with an approximate Space-Saving
+	// sketch of this many counters per worker instead of an exact counter
+	// per product. No other per-product state is kept then: per-product
+	// insights are left empty and distinct products per category are
+	// estimated. Zero keeps exact counts and every per-product insight.
+	TopKCapacity int
+
+	// AnomalyDayWindow and AnomalyMonthWindow are the number of preceding
+	// days or months used as the baseline when scoring anomalies.
+	AnomalyDayWindow, AnomalyMonthWindow int
+
+	// AnomalyThreshold is the minimum absolute z-score flagged as an anomaly
+	AnomalyThreshold float64
+
+	// TopProductsCap and TopRegionsCap limit how many ranked products and
+	// regions are kept for the top endpoints. Zero keeps the full ranking.
+	TopProductsCap, TopRegionsCap int
+
+	// Schema maps the CSV's columns, date format and delimiter. The zero
+	// value reads the default column order.
+	Schema utils.Schema
+}
+
+// DefaultOptions returns the options used by NewConcurrentAggregator
+func DefaultOptions() Options {
+	return Options{
+		DistinctPrecision:  12,
+		RFMBins:            5,
+		Segments:           DefaultSegments(),
+		BasketMaxItems:     50,
+		BasketMaxPairs:     2000000,
+		BasketBufferRows:   1 << 20,
+		BasketMinCount:     2,
+		VelocityMonths:     3,
+		LowStockDays:       14,
+		OverstockDays:      180,
+		ParetoA:            80,
+		ParetoB:            15,
+		PriceBands:         []float64{0, 10, 25, 50, 100, 250, 500, 1000},
+		PriceChangePct:     1,
+		DigestCompression:  100,
+		AnomalyDayWindow:   28,
+		AnomalyMonthWindow: 6,
+		AnomalyThreshold:   3,
+	}
+}
@@ -0,0 +1,162 @@
+package services
+
+import (
+	"sort"
+
+	"github.com/GimhaniHM/backend/internal/models"
+)
+
+// Pareto ranks items by revenue contribution and classifies them into
+// A/B/C tiers. Tiers are resolved on demand so thresholds can vary per query.
+type Pareto struct {
+	// default tier thresholds, in percent of revenue
+	DefaultA, DefaultB float64
+
+	items []models.ParetoItem // ranked, tier left empty
+	total float64
+}
+
+// newPareto ranks items by revenue (desc), breaking ties by ID (asc)
+func newPareto(revenue map[string]float64, names map[string]string, opts Options) *Pareto {
+	p := &Pareto{
+		DefaultA: opts.ParetoA,
+		DefaultB: opts.ParetoB,
+		items:    make([]models.ParetoItem, 0, len(revenue)),
+	}
+	for id, rev := range revenue {
+		p.items = append(p.items, models.ParetoItem{ID: id, Name: names[id], Revenue: rev})
+		p.total += rev
+	}
+	sort.Slice(p.items, func(i, j int) bool {
+		if p.items[i].Revenue != p.items[j].Revenue {
+			return p.items[i].Revenue > p.items[j].Revenue
+		}
+		return p.items[i].ID < p.items[j].ID
+	})
+
+	cum := 0.0
+	for i := range p.items {
+		it := &p.items[i]
+		it.Rank = i + 1
+		cum += it.Revenue
+		if p.total > 0 {
+			it.Share = it.Revenue / p.total
+			it.CumulativeShare = cum / p.total
+		}
+	}
+	return p
+}
+
+// buildParetos ranks products and customers by revenue
+func buildParetos(products productPart, customers customerPart, opts Options) (*Pareto, *Pareto) {
+	prodRev := make(map[string]float64, len(products))
+	prodNames := make(map[string]string, len(products))
+	for id, acc := range products {
+		prodRev[id] = acc.revenue
+		prodNames[id] = acc.name
+	}
+	custRev := make(map[string]float64, len(customers))
+	for id, acc := range customers {
+		custRev[id] = acc.monetary
+	}
+	return newPareto(prodRev, prodNames, opts), newPareto(custRev, nil, opts)
+}
+
+// bounds returns the index where tier A ends and where tier B ends. An item
+// belongs to A while the share of the items ranked above it is below a%,
+// so the top item is always A even when it alone exceeds the threshold.
+func (p *Pareto) bounds(a, b float64) (endA, endB int) {
+	before := func(i int) float64 {
+		if i == 0 {
+			return 0
+		}
+		return p.items[i-1].CumulativeShare * 100
+	}
+	endA = sort.Search(len(p.items), func(i int) bool { return before(i) >= a })
+	endB = sort.Search(len(p.items), func(i int) bool { return before(i) >= a+b })
+	return endA, endB
+}
+
+// Tiers summarises item counts and revenue per tier for thresholds a and b
+// (percent of revenue); tier C receives the remainder.
+func (p *Pareto) Tiers(a, b float64) []models.ParetoTier {
+	endA, endB := p.bounds(a, b)
+	n := len(p.items)
+	ranges := []struct {
+		tier       string
+		start, end int
+	}{{"A", 0, endA}, {"B", endA, endB}, {"C", endB, n}}
+
+	out := make([]models.ParetoTier, 0, 3)
+	for _, r := range ranges {
+		t := models.ParetoTier{Tier: r.tier, Count: r.end - r.start}
+		for _, it := range p.items[r.start:r.end] {
+			t.Revenue += it.Revenue
+		}
+		if n > 0 {
+			t.ItemShare = float64(t.Count) / float64(n)
+		}
+		if p.total > 0 {
+			t.RevenueShare = t.Revenue / p.total
+		}
+		out = append(out, t)
+	}
+	return out
+}
+
+// Page returns ranked items with their tier, optionally restricted to one
+// tier, along with the number of matching items
+func (p *Pareto) Page(a, b float64, tier string, offset, limit int) (int, []models.ParetoItem) {
+	endA, endB := p.bounds(a, b)
+	start, end := 0, len(p.items)
+	switch tier {
+	case "A":
+		end = endA
+	case "B":
+		start, end = endA, endB
+	case "C":
+		start = endB
+	}
+
+	total := end - start
+	if offset > total {
+		offset = total
+	}
+	stop := total
+	if limit < total-offset {
+		stop = offset + limit
+	}
+
+	page := make([]models.ParetoItem, 0, stop-offset)
+	for i := start + offset; i < start+stop; i++ {
+		it := p.items[i]
+		switch {
+		case i < endA:
+			it.Tier = "A"
+		case i < endB:
+			it.Tier = "B"
+		default:
+			it.Tier = "C"
+		}
+		page = append(page, it)
+	}
+	return total, page
+}
+
+// Curve samples the cumulative share curve at the given number of evenly
+// spaced item fractions (plus the origin)
+func (p *Pareto) Curve(points int) []models.CurvePoint {
+	n := len(p.items)
+	out := []models.CurvePoint{{}}
+	if n == 0 {
+		return out
+	}
+	for k := 1; k <= points; k++ {
+		idx := (k*n + points - 1) / points // ceil(k*n/points)
+		out = append(out, models.CurvePoint{
+			ItemShare:    float64(idx) / float64(n),
+			RevenueShare: p.items[idx-1].CumulativeShare,
+		})
+	}
+	return out
+}
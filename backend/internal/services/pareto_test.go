@@ -0,0 +1,55 @@
+package services
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/GimhaniHM/backend/internal/models"
+)
+
+// TestPareto checks ranking, cumulative shares, tier boundaries and paging
+func TestPareto(t *testing.T) {
+	p := newPareto(map[string]float64{
+		"P1": 50, "P2": 30, "P3": 10, "P4": 5, "P5": 5,
+	}, map[string]string{"P1": "One"}, DefaultOptions())
+
+	wantTiers := []models.ParetoTier{
+		{Tier: "A", Count: 2, ItemShare: 0.4, Revenue: 80, RevenueShare: 0.8},
+		{Tier: "B", Count: 2, ItemShare: 0.4, Revenue: 15, RevenueShare: 0.15},
+		{Tier: "C", Count: 1, ItemShare: 0.2, Revenue: 5, RevenueShare: 0.05},
+	}
+	if got := p.Tiers(80, 15); !reflect.DeepEqual(got, wantTiers) {
+		t.Errorf("Tiers(80, 15) = %+v; want %+v", got, wantTiers)
+	}
+
+	total, page := p.Page(80, 15, "", 0, 2)
+	want := []models.ParetoItem{
+		{Rank: 1, ID: "P1", Name: "One", Revenue: 50, Share: 0.5, CumulativeShare: 0.5, Tier: "A"},
+		{Rank: 2, ID: "P2", Revenue: 30, Share: 0.3, CumulativeShare: 0.8, Tier: "A"},
+	}
+	if total != 5 || !reflect.DeepEqual(page, want) {
+		t.Errorf("Page() = %d, %+v; want 5, %+v", total, page, want)
+	}
+
+	// ties are ranked by ID and only the C tier is returned
+	total, page = p.Page(80, 15, "C", 0, 10)
+	if total != 1 || page[0].ID != "P5" || page[0].Rank != 5 || page[0].Tier != "C" {
+		t.Errorf("Page(tier C) = %d, %+v; want P5 ranked 5th", total, page)
+	}
+
+	// a huge limit does not overflow the page bounds
+	if total, page = p.Page(80, 15, "", 1, math.MaxInt); total != 5 || len(page) != 4 {
+		t.Errorf("Page(limit MaxInt) = %d, %d items; want 5, 4", total, len(page))
+	}
+
+	// a dominant top item is still tier A
+	if got := p.Tiers(10, 10); got[0].Count != 1 || got[1].Count != 0 {
+		t.Errorf("Tiers(10, 10) = %+v; want 1 A item and no B items", got)
+	}
+
+	curve := p.Curve(5)
+	if len(curve) != 6 || curve[1].ItemShare != 0.2 || curve[1].RevenueShare != 0.5 || curve[5].RevenueShare != 1 {
+		t.Errorf("Curve(5) = %+v; want 6 points from 0 to 1", curve)
+	}
+}
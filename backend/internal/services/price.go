@@ -0,0 +1,143 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/GimhaniHM/backend/internal/models"
+)
+
+// prices reports each product's average, min and max selling price and
+// detects month-over-month changes in its average selling price of at least
+// opts.PriceChangePct percent, together with the volume either side.
+func (p productPart) prices(opts Options) ([]models.ProductPrice, []models.PriceChange) {
+	out := make([]models.ProductPrice, 0, len(p))
+	changes := make([]models.PriceChange, 0)
+
+	for id, acc := range p {
+		row := models.ProductPrice{
+			ProductID:   id,
+			ProductName: acc.name,
+			Category:    acc.category,
+			MinPrice:    acc.minPrice,
+			MaxPrice:    acc.maxPrice,
+			UnitsSold:   acc.units,
+		}
+		if acc.units > 0 {
+			row.AvgSellingPrice = acc.revenue / float64(acc.units)
+		}
+
+		// Walk the months with sales in order, comparing average prices
+		months := make([]int, 0, len(acc.months))
+		for mi, pm := range acc.months {
+			if pm.units > 0 {
+				months = append(months, mi)
+			}
+		}
+		sort.Ints(months)
+		for i := 1; i < len(months); i++ {
+			prev, cur := acc.months[months[i-1]], acc.months[months[i]]
+			oldPrice := prev.rev / float64(prev.units)
+			newPrice := cur.rev / float64(cur.units)
+			if oldPrice <= 0 {
+				continue
+			}
+			change := (newPrice - oldPrice) / oldPrice * 100
+			if change == 0 || math.Abs(change) < opts.PriceChangePct {
+				continue
+			}
+			volChange := float64(cur.units-prev.units) / float64(prev.units) * 100
+			changes = append(changes, models.PriceChange{
+				ProductID:       id,
+				ProductName:     acc.name,
+				PrevMonth:       monthLabel(months[i-1]),
+				Month:           monthLabel(months[i]),
+				OldPrice:        oldPrice,
+				NewPrice:        newPrice,
+				ChangePct:       change,
+				UnitsBefore:     prev.units,
+				UnitsAfter:      cur.units,
+				VolumeChangePct: volChange,
+				Elasticity:      volChange / change,
+			})
+			row.PriceChanges++
+		}
+		out = append(out, row)
+	}
+
+	// Sort products by units sold (desc), then by ID
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].UnitsSold != out[j].UnitsSold {
+			return out[i].UnitsSold > out[j].UnitsSold
+		}
+		return out[i].ProductID < out[j].ProductID
+	})
+
+	// Sort changes most recent first, then by size of change (desc), then by ID
+	sort.Slice(changes, func(i, j int) bool {
+		a, b := changes[i], changes[j]
+		if a.Month != b.Month {
+			return a.Month > b.Month
+		}
+		if math.Abs(a.ChangePct) != math.Abs(b.ChangePct) {
+			return math.Abs(a.ChangePct) > math.Abs(b.ChangePct)
+		}
+		return a.ProductID < b.ProductID
+	})
+	return out, changes
+}
+
+// priceResults reports average, min and max selling price per category and
+// the price band histograms per category. The "" key holds the histogram
+// across all categories.
+func (p *categoryPart) priceResults() ([]models.CategoryPrice, map[string][]models.PriceBand) {
+	out := make([]models.CategoryPrice, 0, len(p.totals))
+	for k, v := range p.totals {
+		row := models.CategoryPrice{
+			Category:  k,
+			MinPrice:  p.prices[k].min,
+			MaxPrice:  p.prices[k].max,
+			UnitsSold: v.units,
+		}
+		if v.units > 0 {
+			row.AvgSellingPrice = v.rev / float64(v.units)
+		}
+		out = append(out, row)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Category < out[j].Category })
+
+	all := make([]categoryTotals, len(p.bandEdges))
+	bands := make(map[string][]models.PriceBand, len(p.bands)+1)
+	for k, v := range p.bands {
+		bands[k] = p.bandRows(v)
+		for i := range v {
+			all[i].rev += v[i].rev
+			all[i].units += v[i].units
+			all[i].cnt += v[i].cnt
+		}
+	}
+	bands[""] = p.bandRows(all)
+	return out, bands
+}
+
+// bandRows labels per-band totals with their price ranges
+func (p *categoryPart) bandRows(totals []categoryTotals) []models.PriceBand {
+	out := make([]models.PriceBand, len(p.bandEdges))
+	for i, lo := range p.bandEdges {
+		row := models.PriceBand{
+			Band:         fmt.Sprintf("%g+", lo),
+			MinPrice:     lo,
+			Transactions: totals[i].cnt,
+			UnitsSold:    totals[i].units,
+			Revenue:      totals[i].rev,
+		}
+		if i+1 < len(p.bandEdges) {
+			hi := p.bandEdges[i+1]
+			row.Band = fmt.Sprintf("%g-%g", lo, hi)
+			row.MaxPrice = &hi
+		}
+		out[i] = row
+	}
+	return out
+}
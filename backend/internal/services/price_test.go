@@ -0,0 +1,60 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GimhaniHM/backend/internal/models"
+)
+
+// TestPrices checks selling price stats, price change detection and price
+// band histograms
+func TestPrices(t *testing.T) {
+	parse := func(s string) time.Time {
+		d, _ := time.Parse("2006-01-02", s)
+		return d
+	}
+	tx := func(id, date string, price float64, qty int) models.Transaction {
+		return models.Transaction{
+			ProductID: id, Category: "Toys", TransactionDate: parse(date),
+			Price: price, Quantity: qty, TotalPrice: price * float64(qty),
+		}
+	}
+	rows := []models.Transaction{
+		tx("P1", "2024-01-05", 10, 10),
+		tx("P1", "2024-02-05", 10, 10), // no change
+		tx("P1", "2024-03-05", 8, 20),  // -20% price, +100% volume
+		tx("P2", "2024-01-05", 30, 1),
+		tx("P2", "2024-01-06", 30.1, 1), // +0.1% within a month
+	}
+	products := make(productPart)
+	cats := newCategoryPart(Options{PriceBands: []float64{0, 10, 25}})
+	for _, r := range rows {
+		products.add(r)
+		cats.add(r, r.TransactionDate.Format("2006-01"))
+	}
+
+	prices, changes := products.prices(DefaultOptions())
+	p1 := prices[0]
+	if p1.ProductID != "P1" || p1.AvgSellingPrice != 9 || p1.MinPrice != 8 || p1.MaxPrice != 10 || p1.PriceChanges != 1 {
+		t.Errorf("prices[0] = %+v; want P1 avg 9, min 8, max 10, 1 change", p1)
+	}
+	if len(changes) != 1 {
+		t.Fatalf("changes = %+v; want 1", changes)
+	}
+	ch := changes[0]
+	if ch.PrevMonth != "2024-02" || ch.Month != "2024-03" || ch.ChangePct != -20 ||
+		ch.VolumeChangePct != 100 || ch.Elasticity != -5 {
+		t.Errorf("changes[0] = %+v; want -20%% price, +100%% volume, elasticity -5", ch)
+	}
+
+	catPrices, bands := cats.priceResults()
+	if len(catPrices) != 1 || catPrices[0].MinPrice != 8 || catPrices[0].MaxPrice != 30.1 {
+		t.Errorf("category prices = %+v; want Toys 8-30.1", catPrices)
+	}
+	all := bands[""]
+	if len(all) != 3 || all[0].Band != "0-10" || all[0].Transactions != 1 ||
+		all[1].Transactions != 2 || all[2].Band != "25+" || all[2].MaxPrice != nil || all[2].Transactions != 2 {
+		t.Errorf("bands = %+v; want 1, 2 and 2 transactions in 0-10, 10-25, 25+", all)
+	}
+}
@@ -0,0 +1,243 @@
+package services
+
+import (
+	"time"
+
+	"github.com/GimhaniHM/backend/internal/models"
+)
+
+// stockReading is a stock quantity observed on a transaction date
+type stockReading struct {
+	qty int
+	at  time.Time
+}
+
+// supersedes reports whether r is a later reading than o. Readings on the
+// same date keep the lower quantity, so ties err towards stock-out risk.
+func (r stockReading) supersedes(o stockReading) bool {
+	if !r.at.Equal(o.at) {
+		return r.at.After(o.at)
+	}
+	return r.qty < o.qty
+}
+
+// per-month stock and sales for one product
+type productMonth struct {
+	stock stockReading
+	units int
+	rev   float64
+}
+
+// productLabel is a product's latest name and category
+type productLabel struct {
+	name     string
+	category string
+	named    time.Time // date the name/category was last seen
+}
+
+// rename keeps the latest name and category by transaction date. Names seen
+// on the same date are resolved alphabetically so merges are deterministic.
+func (l *productLabel) rename(name, category string, at time.Time) {
+	if l.named.IsZero() || at.After(l.named) || (at.Equal(l.named) && name > l.name) {
+		l.name, l.category, l.named = name, category, at
+	}
+}
+
+// running aggregates for one product ID
+type productAcc struct {
+	productLabel
+	names  map[string]struct{} // every name seen, to report renames and collisions
+	stock  stockReading
+	months map[int]*productMonth // keyed by monthIndex
+
+	units     int
+	revenue   float64
+	minPrice  float64
+	maxPrice  float64
+	added     time.Time // earliest added_date seen
+	firstSale time.Time
+	lastSale  time.Time
+	orders    int
+	countries map[string]categoryTotals
+
+	// daily sales that may fall within launchWindow days of the earliest
+	// added_date, keyed by dayIndex. The earliest date is only final after
+	// the merge, so early sales are measured from it in window.
+	early map[int]seriesPoint
+}
+
+// launchWindow is the longest early-sales window, in days, measured from a
+// product's added_date
+const launchWindow = 90
+
+// addedOn keeps the earliest known added_date, ignoring missing dates, and
+// drops early sales that can no longer fall within its launch window
+func (a *productAcc) addedOn(d time.Time) {
+	if d.IsZero() || (!a.added.IsZero() && !d.Before(a.added)) {
+		return
+	}
+	a.added = d
+	end := dayIndex(d) + launchWindow
+	for day := range a.early {
+		if day >= end {
+			delete(a.early, day)
+		}
+	}
+}
+
+// sold records early sales on a day, unless they are already past the
+// launch window of the earliest added_date seen
+func (a *productAcc) sold(day, units int, rev float64) {
+	if !a.added.IsZero() && day >= dayIndex(a.added)+launchWindow {
+		return
+	}
+	if a.early == nil {
+		a.early = make(map[int]seriesPoint)
+	}
+	pt := a.early[day]
+	pt.units += units
+	pt.rev += rev
+	a.early[day] = pt
+}
+
+// window returns the units and revenue sold within the first days after
+// the earliest added_date
+func (a *productAcc) window(days int) (units int, rev float64) {
+	if a.added.IsZero() {
+		return 0, 0
+	}
+	start := dayIndex(a.added)
+	for day, pt := range a.early {
+		if since := day - start; since >= 0 && since < days {
+			units += pt.units
+			rev += pt.rev
+		}
+	}
+	return units, rev
+}
+
+// productPart holds one worker's per-product accumulators keyed by ProductID
+type productPart map[string]*productAcc
+
+// add folds a single transaction into the product's accumulator
+func (p productPart) add(t models.Transaction) {
+	acc := p[t.ProductID]
+	reading := stockReading{qty: t.StockQuantity, at: t.TransactionDate}
+	if acc == nil {
+		acc = &productAcc{
+			productLabel: productLabel{
+				name:     t.ProductName,
+				category: t.Category,
+				named:    t.TransactionDate,
+			},
+			stock:     reading,
+			months:    make(map[int]*productMonth),
+			added:     t.AddedDate,
+			firstSale: t.TransactionDate,
+			lastSale:  t.TransactionDate,
+			minPrice:  t.Price,
+			maxPrice:  t.Price,
+		}
+		p[t.ProductID] = acc
+	}
+	acc.rename(t.ProductName, t.Category, t.TransactionDate)
+	if acc.names == nil {
+		acc.names = make(map[string]struct{})
+	}
+	acc.names[t.ProductName] = struct{}{}
+	if reading.supersedes(acc.stock) {
+		acc.stock = reading
+	}
+
+	mi := monthIndex(t.TransactionDate)
+	pm := acc.months[mi]
+	if pm == nil {
+		pm = &productMonth{stock: reading}
+		acc.months[mi] = pm
+	} else if reading.supersedes(pm.stock) {
+		pm.stock = reading
+	}
+	pm.units += t.Quantity
+	pm.rev += t.TotalPrice
+
+	acc.units += t.Quantity
+	acc.revenue += t.TotalPrice
+	acc.minPrice = min(acc.minPrice, t.Price)
+	acc.maxPrice = max(acc.maxPrice, t.Price)
+	acc.addedOn(t.AddedDate)
+	if t.TransactionDate.Before(acc.firstSale) {
+		acc.firstSale = t.TransactionDate
+	}
+	if t.TransactionDate.After(acc.lastSale) {
+		acc.lastSale = t.TransactionDate
+	}
+	acc.orders++
+	if acc.countries == nil {
+		acc.countries = make(map[string]categoryTotals)
+	}
+	ct := acc.countries[t.Country]
+	ct.rev += t.TotalPrice
+	ct.units += t.Quantity
+	ct.cnt++
+	acc.countries[t.Country] = ct
+	acc.sold(dayIndex(t.TransactionDate), t.Quantity, t.TotalPrice)
+}
+
+// merge combines another worker's accumulators into p
+func (p productPart) merge(o productPart) {
+	for id, v := range o {
+		acc := p[id]
+		if acc == nil {
+			p[id] = v
+			continue
+		}
+		acc.rename(v.name, v.category, v.named)
+		if acc.names == nil {
+			acc.names = make(map[string]struct{})
+		}
+		for n := range v.names {
+			acc.names[n] = struct{}{}
+		}
+		if v.stock.supersedes(acc.stock) {
+			acc.stock = v.stock
+		}
+		for mi, vm := range v.months {
+			pm := acc.months[mi]
+			if pm == nil {
+				acc.months[mi] = vm
+				continue
+			}
+			if vm.stock.supersedes(pm.stock) {
+				pm.stock = vm.stock
+			}
+			pm.units += vm.units
+			pm.rev += vm.rev
+		}
+
+		acc.units += v.units
+		acc.revenue += v.revenue
+		acc.minPrice = min(acc.minPrice, v.minPrice)
+		acc.maxPrice = max(acc.maxPrice, v.maxPrice)
+		acc.addedOn(v.added)
+		if v.firstSale.Before(acc.firstSale) {
+			acc.firstSale = v.firstSale
+		}
+		if v.lastSale.After(acc.lastSale) {
+			acc.lastSale = v.lastSale
+		}
+		acc.orders += v.orders
+		if acc.countries == nil {
+			acc.countries = make(map[string]categoryTotals)
+		}
+		for k, cv := range v.countries {
+			ct := acc.countries[k]
+			ct.rev += cv.rev
+			ct.units += cv.units
+			ct.cnt += cv.cnt
+			acc.countries[k] = ct
+		}
+		for day, pt := range v.early {
+			acc.sold(day, pt.units, pt.rev)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package services
+
+import (
+	"time"
+
+	"github.com/GimhaniHM/backend/internal/models"
+)
+
+// periodIndex numbers a YYYY-MM label by month or a YYYY-MM-DD label by
+// day, so gaps between rows can be measured
+func periodIndex(label string) (int, bool) {
+	if t, err := time.Parse("2006-01", label); err == nil {
+		return monthIndex(t), true
+	}
+	if t, err := time.Parse("2006-01-02", label); err == nil {
+		return dayIndex(t), true
+	}
+	return 0, false
+}
+
+// DeriveSeries computes moving average, year-to-date and running total
+// columns for a chronologically sorted series labelled YYYY-MM or
+// YYYY-MM-DD. The moving average covers the rolling periods ending at each
+// row, counting periods without a row as zero, and is left unset until that
+// much history exists; rolling < 2 disables it. Year-to-date and running
+// totals are only set when cumulative is true.
+func DeriveSeries(labels []string, values []float64, rolling int, cumulative bool) []models.SeriesStats {
+	out := make([]models.SeriesStats, len(values))
+	first := 0
+	if len(labels) > 0 {
+		first, _ = periodIndex(labels[0])
+	}
+
+	var window, ytd, running float64
+	start := 0 // oldest row still inside the window
+	for i, v := range values {
+		idx, _ := periodIndex(labels[i])
+
+		if rolling >= 2 {
+			window += v
+			for {
+				old, _ := periodIndex(labels[start])
+				if old > idx-rolling {
+					break
+				}
+				window -= values[start]
+				start++
+			}
+			if idx-first >= rolling-1 {
+				avg := window / float64(rolling)
+				out[i].MovingAvg = &avg
+			}
+		}
+
+		if cumulative {
+			if i > 0 && labels[i][:4] != labels[i-1][:4] {
+				ytd = 0
+			}
+			ytd += v
+			running += v
+			y, r := ytd, running
+			out[i].YearToDate, out[i].RunningTotal = &y, &r
+		}
+	}
+	return out
+}
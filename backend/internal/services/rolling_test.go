@@ -0,0 +1,29 @@
+package services
+
+import "testing"
+
+// TestDeriveSeries checks moving averages count missing months as zero and
+// year-to-date totals restart each January
+func TestDeriveSeries(t *testing.T) {
+	labels := []string{"2023-11", "2023-12", "2024-01", "2024-03"}
+	values := []float64{10, 20, 30, 60}
+	got := DeriveSeries(labels, values, 2, true)
+
+	wantAvg := []float64{-1, 15, 25, 30} // -1: not enough history
+	wantYTD := []float64{10, 30, 30, 90}
+	for i, s := range got {
+		if (s.MovingAvg == nil) != (wantAvg[i] < 0) || (s.MovingAvg != nil && *s.MovingAvg != wantAvg[i]) {
+			t.Errorf("row %d moving avg = %v; want %v", i, s.MovingAvg, wantAvg[i])
+		}
+		if s.YearToDate == nil || *s.YearToDate != wantYTD[i] {
+			t.Errorf("row %d ytd = %v; want %v", i, s.YearToDate, wantYTD[i])
+		}
+	}
+	if r := got[3].RunningTotal; r == nil || *r != 120 {
+		t.Errorf("running total = %v; want 120", r)
+	}
+
+	if s := DeriveSeries(labels, values, 0, false)[3]; s.MovingAvg != nil || s.YearToDate != nil || s.RunningTotal != nil {
+		t.Errorf("no columns requested but got %+v", s)
+	}
+}
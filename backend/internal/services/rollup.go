@@ -0,0 +1,217 @@
+package services
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/GimhaniHM/backend/internal/models"
+)
+
+// RollupHierarchies names the levels of each hierarchy, outermost first
+var RollupHierarchies = map[string][]string{
+	"geo":  {"country", "region", "product"},
+	"time": {"year", "month"},
+}
+
+// leaf or grouped cell key, one value per level (unused levels are empty)
+type cubeKey [3]string
+
+// cubePart holds one worker's leaf totals per hierarchy
+type cubePart map[string]map[cubeKey]categoryTotals
+
+// creates an empty cubePart with one map per hierarchy
+func newCubePart() cubePart {
+	p := make(cubePart, len(RollupHierarchies))
+	for h := range RollupHierarchies {
+		p[h] = make(map[cubeKey]categoryTotals)
+	}
+	return p
+}
+
+// add folds a transaction into the leaf cell of every hierarchy. product is
+// the geo leaf's product level, left empty when products are not tracked.
+func (p cubePart) add(t models.Transaction, month, product string) {
+	leaves := [...]struct {
+		h string
+		k cubeKey
+	}{
+		{"geo", cubeKey{t.Country, t.Region, product}},
+		{"time", cubeKey{month[:4], month}},
+	}
+	for _, l := range leaves {
+		ct := p[l.h][l.k]
+		ct.rev += t.TotalPrice
+		ct.units += t.Quantity
+		ct.cnt++
+		p[l.h][l.k] = ct
+	}
+}
+
+// merge folds another worker's leaves into p
+func (p cubePart) merge(o cubePart) {
+	for h, cells := range o {
+		for k, v := range cells {
+			ct := p[h][k]
+			ct.rev += v.rev
+			ct.units += v.units
+			ct.cnt += v.cnt
+			p[h][k] = ct
+		}
+	}
+}
+
+// CubeIndex computes rollups and cubes over the leaf totals, caching each
+// result since leaves do not change after aggregation
+type CubeIndex struct {
+	leaves   cubePart
+	products productPart // labels the product (ID) level with its latest name
+
+	mu    sync.Mutex
+	cache map[string][]models.RollupRow
+}
+
+// newCubeIndex wraps merged leaves in a CubeIndex
+func newCubeIndex(leaves cubePart, products productPart) *CubeIndex {
+	return &CubeIndex{leaves: leaves, products: products, cache: make(map[string][]models.RollupRow)}
+}
+
+// Rollup aggregates a hierarchy at every grouping set and marks each row
+// with its grouping. As a rollup the grouping sets are the level prefixes
+// (e.g. country, country+region, ...) plus the grand total, ordered as a
+// tree: each subtotal precedes its children, siblings by revenue (desc) then
+// name. As a cube every combination of levels is produced, ordered by
+// grouping, then revenue (desc) and key. Sales without a product ID (every
+// sale when products are not tracked) count towards the geo subtotals but
+// get no product rows. ok is false for an unknown hierarchy.
+func (c *CubeIndex) Rollup(hierarchy string, cube bool) ([]models.RollupRow, bool) {
+	levels, ok := RollupHierarchies[hierarchy]
+	if !ok {
+		return nil, false
+	}
+	ck := hierarchy
+	if cube {
+		ck += "/cube"
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if rows, ok := c.cache[ck]; ok {
+		return rows, true
+	}
+
+	// Grouping sets as GROUPING_ID masks: bit n-1-i set when level i is rolled up
+	n := len(levels)
+	var masks []int
+	if cube {
+		for m := 0; m < 1<<n; m++ {
+			masks = append(masks, m)
+		}
+	} else {
+		for kept := n; kept >= 0; kept-- {
+			masks = append(masks, 1<<(n-kept)-1)
+		}
+	}
+
+	type group struct {
+		mask int
+		key  cubeKey
+	}
+	totals := make(map[group]categoryTotals)
+	for leaf, v := range c.leaves[hierarchy] {
+		for _, m := range masks {
+			if hierarchy == "geo" && m&1 == 0 && leaf[2] == "" {
+				continue // no product to group by
+			}
+			g := group{mask: m}
+			for i := 0; i < n; i++ {
+				if m&(1<<(n-1-i)) == 0 {
+					g.key[i] = leaf[i]
+				}
+			}
+			ct := totals[g]
+			ct.rev += v.rev
+			ct.units += v.units
+			ct.cnt += v.cnt
+			totals[g] = ct
+		}
+	}
+
+	rows := make([]models.RollupRow, 0, len(totals))
+	for g, v := range totals {
+		kept := make([]string, 0, n)
+		for i := 0; i < n; i++ {
+			if g.mask&(1<<(n-1-i)) == 0 {
+				kept = append(kept, levels[i])
+			}
+		}
+		level := strings.Join(kept, "+")
+		if level == "" {
+			level = "total"
+		}
+		row := models.RollupRow{
+			Key:              append([]string(nil), g.key[:n]...),
+			Grouping:         g.mask,
+			Level:            level,
+			TotalRevenue:     v.rev,
+			UnitsSold:        v.units,
+			TransactionCount: v.cnt,
+		}
+		if hierarchy == "geo" && g.mask&1 == 0 {
+			if acc := c.products[g.key[2]]; acc != nil {
+				row.ProductName = acc.name
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	if cube {
+		sort.Slice(rows, func(i, j int) bool {
+			a, b := rows[i], rows[j]
+			if a.Grouping != b.Grouping {
+				return a.Grouping < b.Grouping
+			}
+			if a.TotalRevenue != b.TotalRevenue {
+				return a.TotalRevenue > b.TotalRevenue
+			}
+			return strings.Join(a.Key, "\x00") < strings.Join(b.Key, "\x00")
+		})
+	} else {
+		sortRollupTree(rows, n, func(depth int, key []string) float64 {
+			g := group{mask: 1<<(n-depth) - 1}
+			copy(g.key[:depth], key[:depth])
+			return totals[g].rev
+		})
+	}
+	c.cache[ck] = rows
+	return rows, true
+}
+
+// sortRollupTree orders rollup rows depth first: at the first level where
+// two rows' ancestors differ, the ancestor with more revenue (then the lower
+// name) goes first, and a subtotal precedes its descendants
+func sortRollupTree(rows []models.RollupRow, n int, revenue func(depth int, key []string) float64) {
+	depth := func(r models.RollupRow) int {
+		d := 0
+		for d < n && r.Grouping&(1<<(n-1-d)) == 0 {
+			d++
+		}
+		return d
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		a, b := rows[i], rows[j]
+		da, db := depth(a), depth(b)
+		for d := 0; d < n; d++ {
+			if a.Key[d] != b.Key[d] && d < da && d < db {
+				ra, rb := revenue(d+1, a.Key), revenue(d+1, b.Key)
+				if ra != rb {
+					return ra > rb
+				}
+				return a.Key[d] < b.Key[d]
+			}
+			if da == d || db == d {
+				return da < db
+			}
+		}
+		return false
+	})
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/GimhaniHM/backend/internal/models"
+)
+
+// TestRollup checks subtotals and grand totals come out as a tree with
+// grouping markers, and that a cube adds the non-prefix grouping sets
+func TestRollup(t *testing.T) {
+	cube := newCubePart()
+	tx := func(country, region, product string, rev float64) {
+		cube.add(models.Transaction{Country: country, Region: region, ProductID: product, Quantity: 1, TotalPrice: rev}, "2024-01", product)
+	}
+	tx("UK", "North", "Prod1", 10)
+	tx("USA", "West", "Prod1", 20)
+	tx("USA", "West", "Prod2", 5)
+	tx("USA", "East", "Prod2", 30)
+	idx := newCubeIndex(cube, nil)
+
+	rows, ok := idx.Rollup("geo", false)
+	if !ok {
+		t.Fatalf("Rollup(geo) not ok")
+	}
+	type row struct {
+		key      [3]string
+		grouping int
+		rev      float64
+	}
+	got := make([]row, len(rows))
+	for i, r := range rows {
+		got[i] = row{[3]string{r.Key[0], r.Key[1], r.Key[2]}, r.Grouping, r.TotalRevenue}
+	}
+	want := []row{
+		{[3]string{"", "", ""}, 7, 65},
+		{[3]string{"USA", "", ""}, 3, 55},
+		{[3]string{"USA", "East", ""}, 1, 30},
+		{[3]string{"USA", "East", "Prod2"}, 0, 30},
+		{[3]string{"USA", "West", ""}, 1, 25},
+		{[3]string{"USA", "West", "Prod1"}, 0, 20},
+		{[3]string{"USA", "West", "Prod2"}, 0, 5},
+		{[3]string{"UK", "", ""}, 3, 10},
+		{[3]string{"UK", "North", ""}, 1, 10},
+		{[3]string{"UK", "North", "Prod1"}, 0, 10},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Rollup(geo) = %+v; want %+v", got, want)
+	}
+	if rows[0].Level != "total" || rows[2].Level != "country+region" {
+		t.Errorf("levels = %q, %q; want total, country+region", rows[0].Level, rows[2].Level)
+	}
+
+	cubeRows, _ := idx.Rollup("geo", true)
+	byProduct := 0
+	for _, r := range cubeRows {
+		if r.Grouping == 6 { // country and region rolled up
+			byProduct++
+		}
+	}
+	if len(cubeRows) != 22 || byProduct != 2 {
+		t.Errorf("Rollup(geo, cube) = %d rows, %d by product; want 22 and 2", len(cubeRows), byProduct)
+	}
+
+	if _, ok := idx.Rollup("city", false); ok {
+		t.Errorf("unknown hierarchy accepted")
+	}
+}
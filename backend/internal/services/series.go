@@ -0,0 +1,159 @@
+package services
+
+import (
+	"sort"
+	"time"
+
+	"github.com/GimhaniHM/backend/internal/models"
+)
+
+// SeriesDimensions are the groupings with daily revenue and unit series.
+// "total" has a single key, "all".
+var SeriesDimensions = []string{"total", "country", "region", "category"}
+
+// Series granularities and metrics
+const (
+	GranularityDay   = "day"
+	GranularityMonth = "month"
+
+	MetricRevenue = "revenue"
+	MetricUnits   = "units"
+)
+
+// dayIndex numbers calendar days since the Unix epoch
+func dayIndex(t time.Time) int {
+	return int(t.Unix() / 86400)
+}
+
+// dayLabel formats a day index as YYYY-MM-DD
+func dayLabel(idx int) string {
+	return time.Unix(int64(idx)*86400, 0).UTC().Format("2006-01-02")
+}
+
+// identifies one series
+type seriesKey struct{ dim, key string }
+
+// revenue and units sold in one period
+type seriesPoint struct {
+	rev   float64
+	units int
+}
+
+// seriesPart holds one worker's daily totals per series, keyed by day index
+type seriesPart map[seriesKey]map[int]seriesPoint
+
+// add folds a transaction into the daily totals of every dimension. Rows
+// without a transaction date are ignored: zero-filling from year 1 would
+// produce hundreds of thousands of empty periods.
+func (p seriesPart) add(t models.Transaction) {
+	if t.TransactionDate.IsZero() {
+		return
+	}
+	day := dayIndex(t.TransactionDate)
+	keys := [...]seriesKey{
+		{"total", "all"},
+		{"country", t.Country},
+		{"region", t.Region},
+		{"category", t.Category},
+	}
+	for _, k := range keys {
+		days := p[k]
+		if days == nil {
+			days = make(map[int]seriesPoint)
+			p[k] = days
+		}
+		pt := days[day]
+		pt.rev += t.TotalPrice
+		pt.units += t.Quantity
+		days[day] = pt
+	}
+}
+
+// merge folds another worker's totals into p
+func (p seriesPart) merge(o seriesPart) {
+	for k, days := range o {
+		mine := p[k]
+		if mine == nil {
+			p[k] = days
+			continue
+		}
+		for d, v := range days {
+			pt := mine[d]
+			pt.rev += v.rev
+			pt.units += v.units
+			mine[d] = pt
+		}
+	}
+}
+
+// SeriesIndex serves daily or monthly revenue and unit series per country,
+// region and category
+type SeriesIndex struct {
+	// default anomaly baselines (in periods) and z-score threshold
+	DayWindow, MonthWindow int
+	Threshold              float64
+
+	days seriesPart
+}
+
+// Keys lists the members of a dimension in ascending order
+func (s *SeriesIndex) Keys(dim string) []string {
+	keys := make([]string, 0)
+	for k := range s.days {
+		if k.dim == dim {
+			keys = append(keys, k.key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Series returns one metric of a series as consecutive periods from its
+// first to its last sale, with zero for periods without sales. ok is false
+// for an unknown series, granularity or metric.
+func (s *SeriesIndex) Series(dim, key, granularity, metric string) (labels []string, values []float64, ok bool) {
+	days, found := s.days[seriesKey{dim, key}]
+	if !found || (metric != MetricRevenue && metric != MetricUnits) {
+		return nil, nil, false
+	}
+
+	// Bucket by period index and render labels for the chosen granularity
+	buckets := make(map[int]float64, len(days))
+	var label func(int) string
+	switch granularity {
+	case GranularityDay:
+		label = dayLabel
+	case GranularityMonth:
+		label = monthLabel
+	default:
+		return nil, nil, false
+	}
+	first, last := 0, 0
+	seen := false
+	for d, pt := range days {
+		idx := d
+		if granularity == GranularityMonth {
+			idx = monthIndex(time.Unix(int64(d)*86400, 0).UTC())
+		}
+		v := pt.rev
+		if metric == MetricUnits {
+			v = float64(pt.units)
+		}
+		buckets[idx] += v
+		if !seen || idx < first {
+			first = idx
+		}
+		if !seen || idx > last {
+			last = idx
+		}
+		seen = true
+	}
+
+	labels = make([]string, 0, last-first+1)
+	values = make([]float64, 0, last-first+1)
+	for idx := first; idx <= last && seen; idx++ {
+		labels = append(labels, label(idx))
+		values = append(values, buckets[idx])
+	}
+	return labels, values, true
+}
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"math"
+	"sort"
+
+	"github.com/GimhaniHM/backend/internal/models"
+	"github.com/GimhaniHM/backend/internal/sketch"
+)
+
+// productCounter counts units sold per product ID, either exactly or, when
+// opts.TopKCapacity is set, with a Space-Saving sketch of that many
+// counters. In approximate mode no other per-product state is kept, so the
+// counter labels the keys it tracks itself and drops a label when the
+// sketch evicts its key.
+type productCounter struct {
+	exact  map[string]int
+	approx *sketch.SpaceSaving
+	labels map[string]*topkLabel // approximate mode only
+}
+
+// topkLabel is the latest name, category and stock of a tracked product
+type topkLabel struct {
+	productLabel
+	stock stockReading
+}
+
+// update keeps the latest name, category and stock of l and o
+func (l *topkLabel) update(o topkLabel) {
+	l.rename(o.name, o.category, o.named)
+	if o.stock.supersedes(l.stock) {
+		l.stock = o.stock
+	}
+}
+
+// creates an empty productCounter in the mode selected by opts
+func newProductCounter(opts Options) *productCounter {
+	c := &productCounter{}
+	if opts.TopKCapacity > 0 {
+		c.approx = sketch.NewSpaceSaving(opts.TopKCapacity)
+		c.labels = make(map[string]*topkLabel)
+	} else {
+		c.exact = make(map[string]int)
+	}
+	return c
+}
+
+// add records the units sold on a transaction
+func (c *productCounter) add(t models.Transaction) {
+	if c.approx == nil {
+		c.exact[t.ProductID] += t.Quantity
+		return
+	}
+	if evicted, ok := c.approx.Add(t.ProductID, int64(t.Quantity)); ok {
+		delete(c.labels, evicted)
+	}
+	seen := topkLabel{
+		productLabel: productLabel{name: t.ProductName, category: t.Category, named: t.TransactionDate},
+		stock:        stockReading{qty: t.StockQuantity, at: t.TransactionDate},
+	}
+	if l := c.labels[t.ProductID]; l != nil {
+		l.update(seen)
+	} else {
+		c.labels[t.ProductID] = &seen
+	}
+}
+
+// merge folds another worker's counter into c
+func (c *productCounter) merge(o *productCounter) {
+	if c.approx != nil {
+		c.approx.Merge(o.approx)
+		for id, ol := range o.labels {
+			if l := c.labels[id]; l != nil {
+				l.update(*ol)
+			} else {
+				c.labels[id] = ol
+			}
+		}
+		// Merging may displace keys of either side
+		for id := range c.labels {
+			if !c.approx.Contains(id) {
+				delete(c.labels, id)
+			}
+		}
+		return
+	}
+	for k, v := range o.exact {
+		c.exact[k] += v
+	}
+}
+
+// top returns the n products with the most units sold (every product when
+// n is zero), labelled with their latest name, category and stock from
+// products, or from the counter's own labels in approximate mode.
+// Approximate counts are upper bounds; MaxError says how far each may
+// overcount.
+func (c *productCounter) top(n int, products productPart) []models.ProductFrequency {
+	row := func(id string, count int) models.ProductFrequency {
+		pf := models.ProductFrequency{ProductID: id, PurchaseCount: count}
+		if l := c.labels[id]; l != nil {
+			pf.ProductName, pf.Category, pf.StockQuantity = l.name, l.category, l.stock.qty
+		} else if acc := products[id]; acc != nil {
+			pf.ProductName, pf.Category, pf.StockQuantity = acc.name, acc.category, acc.stock.qty
+		}
+		return pf
+	}
+
+	var tp []models.ProductFrequency
+	if c.approx != nil {
+		hits := c.approx.Top(math.MaxInt)
+		tp = make([]models.ProductFrequency, 0, len(hits))
+		for _, h := range hits {
+			pf := row(h.Key, int(h.Count))
+			pf.MaxError = int(h.Err)
+			tp = append(tp, pf)
+		}
+	} else {
+		tp = make([]models.ProductFrequency, 0, len(c.exact))
+		for k, v := range c.exact {
+			tp = append(tp, row(k, v))
+		}
+	}
+
+	// Sort top products by purchase count, then name (desc) and ID
+	sort.Slice(tp, func(i, j int) bool {
+		if tp[i].PurchaseCount != tp[j].PurchaseCount {
+			return tp[i].PurchaseCount > tp[j].PurchaseCount
+		}
+		if tp[i].ProductName != tp[j].ProductName {
+			return tp[i].ProductName > tp[j].ProductName
+		}
+		return tp[i].ProductID < tp[j].ProductID
+	})
+	if n > 0 && len(tp) > n {
+		tp = tp[:n]
+	}
+	return tp
+}
@@ -0,0 +1,135 @@
+package sketch
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/bits"
+)
+
+// DistinctCounter counts distinct string keys. Implementations of the same
+// concrete type can be merged, which lets per-worker partials be combined.
+type DistinctCounter interface {
+	Add(key string)
+	Merge(other DistinctCounter)
+	Count() uint64
+}
+
+// HyperLogLog is an approximate distinct counter with a fixed memory
+// footprint of 2^precision bytes and a standard error of ~1.04/sqrt(2^precision).
+type HyperLogLog struct {
+	p   uint8
+	reg []uint8
+}
+
+// NewHyperLogLog creates an empty sketch. Precision must be between 4 and 18.
+func NewHyperLogLog(precision uint8) *HyperLogLog {
+	if precision < 4 || precision > 18 {
+		panic(fmt.Sprintf("sketch: invalid HyperLogLog precision %d", precision))
+	}
+	return &HyperLogLog{p: precision, reg: make([]uint8, 1<<precision)}
+}
+
+// Add records a key in the sketch
+func (h *HyperLogLog) Add(key string) {
+	x := hash64(key)
+	idx := x >> (64 - h.p)
+	// rank of the first set bit in the remaining bits (1-based)
+	w := x<<h.p | 1<<(h.p-1)
+	rank := uint8(bits.LeadingZeros64(w)) + 1
+	if rank > h.reg[idx] {
+		h.reg[idx] = rank
+	}
+}
+
+// Merge folds another HyperLogLog of the same precision into h
+func (h *HyperLogLog) Merge(other DistinctCounter) {
+	o, ok := other.(*HyperLogLog)
+	if !ok || o.p != h.p {
+		panic("sketch: cannot merge HyperLogLog with a different counter")
+	}
+	for i, r := range o.reg {
+		if r > h.reg[i] {
+			h.reg[i] = r
+		}
+	}
+}
+
+// Count returns the estimated number of distinct keys
+func (h *HyperLogLog) Count() uint64 {
+	m := float64(len(h.reg))
+	sum := 0.0
+	zeros := 0
+	for _, r := range h.reg {
+		sum += 1 / float64(uint64(1)<<r)
+		if r == 0 {
+			zeros++
+		}
+	}
+	est := alpha(len(h.reg)) * m * m / sum
+
+	// small-range correction: fall back to linear counting
+	if est <= 2.5*m && zeros > 0 {
+		est = m * math.Log(m/float64(zeros))
+	}
+	return uint64(est + 0.5)
+}
+
+// bias correction constant for m registers
+func alpha(m int) float64 {
+	switch m {
+	case 16:
+		return 0.673
+	case 32:
+		return 0.697
+	case 64:
+		return 0.709
+	}
+	return 0.7213 / (1 + 1.079/float64(m))
+}
+
+// hash64 hashes a key with FNV-1a and a splitmix64 finaliser, since plain
+// FNV does not spread short, similar keys (U123, U124) well enough for HLL.
+func hash64(key string) uint64 {
+	f := fnv.New64a()
+	f.Write([]byte(key))
+	x := f.Sum64()
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// ExactCounter counts distinct keys exactly using a set. Memory grows with
+// the number of keys, so it is intended for small datasets.
+type ExactCounter struct {
+	keys map[string]struct{}
+}
+
+// NewExactCounter creates an empty exact counter
+func NewExactCounter() *ExactCounter {
+	return &ExactCounter{keys: make(map[string]struct{})}
+}
+
+// Add records a key in the set
+func (e *ExactCounter) Add(key string) {
+	e.keys[key] = struct{}{}
+}
+
+// Merge folds another ExactCounter into e
+func (e *ExactCounter) Merge(other DistinctCounter) {
+	o, ok := other.(*ExactCounter)
+	if !ok {
+		panic("sketch: cannot merge ExactCounter with a different counter")
+	}
+	for k := range o.keys {
+		e.keys[k] = struct{}{}
+	}
+}
+
+// Count returns the number of distinct keys
+func (e *ExactCounter) Count() uint64 {
+	return uint64(len(e.keys))
+}
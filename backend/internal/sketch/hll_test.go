@@ -0,0 +1,55 @@
+package sketch
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// TestHyperLogLogAccuracy checks the estimate stays within a few standard
+// errors of the true cardinality, including after merging partials
+func TestHyperLogLogAccuracy(t *testing.T) {
+	const n = 100000
+	a := NewHyperLogLog(12)
+	b := NewHyperLogLog(12)
+	for i := 0; i < n; i++ {
+		key := fmt.Sprintf("U%d", i)
+		if i%2 == 0 {
+			a.Add(key)
+		} else {
+			b.Add(key)
+		}
+		// duplicates must not change the estimate
+		a.Add(key)
+	}
+	a.Merge(b)
+
+	got := float64(a.Count())
+	if relErr := math.Abs(got-n) / n; relErr > 0.05 {
+		t.Errorf("Count() = %.0f; want %d within 5%% (err %.3f)", got, n, relErr)
+	}
+}
+
+// TestHyperLogLogSmallRange checks linear counting keeps small sets accurate
+func TestHyperLogLogSmallRange(t *testing.T) {
+	h := NewHyperLogLog(12)
+	for i := 0; i < 10; i++ {
+		h.Add(fmt.Sprintf("U%d", i))
+	}
+	if got := h.Count(); got != 10 {
+		t.Errorf("Count() = %d; want 10", got)
+	}
+}
+
+// TestExactCounterMerge checks exact sets union correctly
+func TestExactCounterMerge(t *testing.T) {
+	a, b := NewExactCounter(), NewExactCounter()
+	a.Add("U1")
+	a.Add("U2")
+	b.Add("U2")
+	b.Add("U3")
+	a.Merge(b)
+	if got := a.Count(); got != 3 {
+		t.Errorf("Count() = %d; want 3", got)
+	}
+}
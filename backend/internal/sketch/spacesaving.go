@@ -0,0 +1,170 @@
+package sketch
+
+import (
+	"container/heap"
+	"fmt"
+	"sort"
+)
+
+// HeavyHitter is a key reported by SpaceSaving. Count overestimates the
+// true count by at most Err.
+type HeavyHitter struct {
+	Key   string
+	Count int64
+	Err   int64
+}
+
+// SpaceSaving finds the heaviest keys of a weighted stream using a fixed
+// number of counters (Metwally et al.). Any key whose true count exceeds
+// Total()/capacity is guaranteed to be tracked, and each tracked count is
+// at most Total()/capacity above the true count. Sketches are mergeable.
+type SpaceSaving struct {
+	capacity int
+	items    map[string]*ssItem
+	heap     ssHeap
+	total    int64
+}
+
+// ssItem is one counter, positioned in the min-heap at idx
+type ssItem struct {
+	HeavyHitter
+	idx int
+}
+
+// NewSpaceSaving creates an empty sketch with capacity counters
+func NewSpaceSaving(capacity int) *SpaceSaving {
+	if capacity < 1 {
+		panic(fmt.Sprintf("sketch: invalid SpaceSaving capacity %d", capacity))
+	}
+	return &SpaceSaving{capacity: capacity, items: make(map[string]*ssItem, capacity)}
+}
+
+// Add adds w to key's count. When every counter is in use the smallest one
+// is taken over by key; the displaced key is returned so callers can drop
+// any state they keep alongside it.
+func (s *SpaceSaving) Add(key string, w int64) (evicted string, ok bool) {
+	s.total += w
+	if it := s.items[key]; it != nil {
+		it.Count += w
+		heap.Fix(&s.heap, it.idx)
+		return "", false
+	}
+	if len(s.heap) < s.capacity {
+		it := &ssItem{HeavyHitter: HeavyHitter{Key: key, Count: w}}
+		s.items[key] = it
+		heap.Push(&s.heap, it)
+		return "", false
+	}
+
+	// Replace the minimum counter, inheriting its count as error
+	it := s.heap[0]
+	delete(s.items, it.Key)
+	evicted = it.Key
+	it.Key, it.Err, it.Count = key, it.Count, it.Count+w
+	s.items[key] = it
+	heap.Fix(&s.heap, 0)
+	return evicted, true
+}
+
+// Contains reports whether key currently holds a counter
+func (s *SpaceSaving) Contains(key string) bool {
+	_, ok := s.items[key]
+	return ok
+}
+
+// Total returns the sum of all weights added
+func (s *SpaceSaving) Total() int64 { return s.total }
+
+// floor is the most an untracked key can have been seen: the smallest
+// count once all counters are in use, and zero before that
+func (s *SpaceSaving) floor() int64 {
+	if len(s.heap) < s.capacity {
+		return 0
+	}
+	return s.heap[0].Count
+}
+
+// Merge folds another sketch into s (Agarwal et al.): keys missing from one
+// side are charged that side's floor as both count and error, then the
+// largest capacity counters are kept.
+func (s *SpaceSaving) Merge(o *SpaceSaving) {
+	fs, fo := s.floor(), o.floor()
+	all := make([]HeavyHitter, 0, len(s.items)+len(o.items))
+	for k, it := range s.items {
+		h := it.HeavyHitter
+		if ot := o.items[k]; ot != nil {
+			h.Count += ot.Count
+			h.Err += ot.Err
+		} else {
+			h.Count += fo
+			h.Err += fo
+		}
+		all = append(all, h)
+	}
+	for k, ot := range o.items {
+		if s.items[k] == nil {
+			h := ot.HeavyHitter
+			h.Count += fs
+			h.Err += fs
+			all = append(all, h)
+		}
+	}
+	sortHitters(all)
+	if len(all) > s.capacity {
+		all = all[:s.capacity]
+	}
+
+	s.total += o.total
+	s.items = make(map[string]*ssItem, s.capacity)
+	s.heap = make(ssHeap, 0, s.capacity)
+	for _, h := range all {
+		it := &ssItem{HeavyHitter: h}
+		s.items[h.Key] = it
+		heap.Push(&s.heap, it)
+	}
+}
+
+// Top returns up to n tracked keys by count (desc), then key (asc)
+func (s *SpaceSaving) Top(n int) []HeavyHitter {
+	out := make([]HeavyHitter, 0, len(s.items))
+	for _, it := range s.items {
+		out = append(out, it.HeavyHitter)
+	}
+	sortHitters(out)
+	if len(out) > n {
+		out = out[:n]
+	}
+	return out
+}
+
+// sortHitters orders by count (desc), then key (asc)
+func sortHitters(hs []HeavyHitter) {
+	sort.Slice(hs, func(i, j int) bool {
+		if hs[i].Count != hs[j].Count {
+			return hs[i].Count > hs[j].Count
+		}
+		return hs[i].Key < hs[j].Key
+	})
+}
+
+// ssHeap is a min-heap of counters by count
+type ssHeap []*ssItem
+
+func (h ssHeap) Len() int           { return len(h) }
+func (h ssHeap) Less(i, j int) bool { return h[i].Count < h[j].Count }
+func (h ssHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].idx = i
+	h[j].idx = j
+}
+func (h *ssHeap) Push(x any) {
+	it := x.(*ssItem)
+	it.idx = len(*h)
+	*h = append(*h, it)
+}
+func (h *ssHeap) Pop() any {
+	old := *h
+	it := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return it
+}
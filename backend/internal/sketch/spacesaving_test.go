@@ -0,0 +1,66 @@
+package sketch
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestSpaceSavingHeavyHitters checks that frequent keys survive a long tail
+// of rare keys, across merged partials, with counts inside their bounds
+func TestSpaceSavingHeavyHitters(t *testing.T) {
+	exact := make(map[string]int64)
+	parts := []*SpaceSaving{NewSpaceSaving(50), NewSpaceSaving(50), NewSpaceSaving(50)}
+	for i := 0; i < 30000; i++ {
+		key := fmt.Sprintf("tail%d", i)
+		if i%3 == 0 {
+			key = fmt.Sprintf("hot%d", i%5)
+		}
+		w := int64(1 + i%4)
+		exact[key] += w
+		parts[i%len(parts)].Add(key, w)
+	}
+	s := parts[0]
+	s.Merge(parts[1])
+	s.Merge(parts[2])
+
+	var total int64
+	for _, v := range exact {
+		total += v
+	}
+	if s.Total() != total {
+		t.Fatalf("Total() = %d; want %d", s.Total(), total)
+	}
+
+	top := s.Top(5)
+	if len(top) != 5 {
+		t.Fatalf("Top(5) returned %d keys", len(top))
+	}
+	for _, h := range top {
+		if h.Key[:3] != "hot" {
+			t.Errorf("unexpected heavy hitter %+v", h)
+		}
+		if want := exact[h.Key]; h.Count < want || h.Count-h.Err > want {
+			t.Errorf("%s: count %d err %d; true count %d outside bounds", h.Key, h.Count, h.Err, want)
+		}
+	}
+}
+
+// TestSpaceSavingEviction checks the displaced key is reported and the new
+// key inherits the minimum count as error
+func TestSpaceSavingEviction(t *testing.T) {
+	s := NewSpaceSaving(2)
+	s.Add("a", 5)
+	s.Add("b", 2)
+	if _, ok := s.Add("a", 1); ok {
+		t.Fatalf("Add of a tracked key evicted another")
+	}
+	ev, ok := s.Add("c", 1)
+	if !ok || ev != "b" || s.Contains("b") {
+		t.Fatalf("Add(c) evicted %q, %v; want b", ev, ok)
+	}
+	got := s.Top(10)
+	want := []HeavyHitter{{Key: "a", Count: 6}, {Key: "c", Count: 3, Err: 2}}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Top = %+v; want %+v", got, want)
+	}
+}
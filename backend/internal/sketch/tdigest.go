@@ -0,0 +1,191 @@
+package sketch
+
+import (
+	"math"
+	"sort"
+)
+
+// centroid is a cluster of samples summarised by its mean and weight
+type centroid struct {
+	mean   float64
+	weight float64
+}
+
+// TDigest is a mergeable quantile sketch (Dunning's merging t-digest).
+// Accuracy is best near the tails; memory is bounded by the compression.
+type TDigest struct {
+	compression float64
+	centroids   []centroid // sorted by mean after compress
+	buffer      []centroid // samples not yet merged
+	count       float64
+	sum         float64
+	min, max    float64
+}
+
+// NewTDigest creates an empty digest. Higher compression keeps more
+// centroids and gives more accurate quantiles (100 is a common choice).
+func NewTDigest(compression float64) *TDigest {
+	return &TDigest{
+		compression: compression,
+		min:         math.Inf(1),
+		max:         math.Inf(-1),
+	}
+}
+
+// Add records a single sample
+func (d *TDigest) Add(x float64) {
+	d.buffer = append(d.buffer, centroid{mean: x, weight: 1})
+	d.count++
+	d.sum += x
+	d.min = math.Min(d.min, x)
+	d.max = math.Max(d.max, x)
+	if len(d.buffer) >= int(5*d.compression) {
+		d.compress()
+	}
+}
+
+// Merge folds another digest into d
+func (d *TDigest) Merge(o *TDigest) {
+	if o.count == 0 {
+		return
+	}
+	d.buffer = append(d.buffer, o.centroids...)
+	d.buffer = append(d.buffer, o.buffer...)
+	d.count += o.count
+	d.sum += o.sum
+	d.min = math.Min(d.min, o.min)
+	d.max = math.Max(d.max, o.max)
+	d.compress()
+}
+
+// compress merges buffered samples into the centroid list
+func (d *TDigest) compress() {
+	if len(d.buffer) == 0 {
+		return
+	}
+	d.centroids = d.mergeCentroids(append(d.centroids, d.buffer...))
+	d.buffer = d.buffer[:0]
+}
+
+// merged returns the compressed centroids without modifying d, so reads
+// are safe to run concurrently once the digest is no longer being written
+func (d *TDigest) merged() []centroid {
+	if len(d.buffer) == 0 {
+		return d.centroids
+	}
+	all := make([]centroid, 0, len(d.centroids)+len(d.buffer))
+	all = append(all, d.centroids...)
+	return d.mergeCentroids(append(all, d.buffer...))
+}
+
+// mergeCentroids sorts centroids by mean and combines neighbours, keeping
+// each result within one unit of the arcsine scale function
+func (d *TDigest) mergeCentroids(all []centroid) []centroid {
+	sort.Slice(all, func(i, j int) bool { return all[i].mean < all[j].mean })
+
+	scale := func(q float64) float64 { return d.compression / (2 * math.Pi) * math.Asin(2*q-1) }
+	inverse := func(k float64) float64 { return (math.Sin(k*2*math.Pi/d.compression) + 1) / 2 }
+
+	out := make([]centroid, 0, int(2*d.compression))
+	cur := all[0]
+	done := 0.0
+	limit := d.count * inverse(scale(0)+1)
+	for _, next := range all[1:] {
+		if done+cur.weight+next.weight <= limit {
+			cur.mean += (next.mean - cur.mean) * next.weight / (cur.weight + next.weight)
+			cur.weight += next.weight
+			continue
+		}
+		out = append(out, cur)
+		done += cur.weight
+		limit = d.count * inverse(scale(done/d.count)+1)
+		cur = next
+	}
+	return append(out, cur)
+}
+
+// Count returns the number of samples
+func (d *TDigest) Count() float64 { return d.count }
+
+// Mean returns the mean of all samples (0 if empty)
+func (d *TDigest) Mean() float64 {
+	if d.count == 0 {
+		return 0
+	}
+	return d.sum / d.count
+}
+
+// Min returns the smallest sample (0 if empty)
+func (d *TDigest) Min() float64 {
+	if d.count == 0 {
+		return 0
+	}
+	return d.min
+}
+
+// Max returns the largest sample (0 if empty)
+func (d *TDigest) Max() float64 {
+	if d.count == 0 {
+		return 0
+	}
+	return d.max
+}
+
+// Quantile estimates the value at quantile q (0-1) by interpolating between
+// centroid centres. Returns 0 for an empty digest.
+func (d *TDigest) Quantile(q float64) float64 {
+	if d.count == 0 {
+		return 0
+	}
+	if q <= 0 {
+		return d.min
+	}
+	if q >= 1 {
+		return d.max
+	}
+
+	target := q * d.count
+	prevX, prevW := d.min, 0.0
+	cum := 0.0
+	for _, c := range d.merged() {
+		center := cum + c.weight/2
+		if target < center {
+			return lerp(prevW, prevX, center, c.mean, target)
+		}
+		prevX, prevW = c.mean, center
+		cum += c.weight
+	}
+	return lerp(prevW, prevX, d.count, d.max, target)
+}
+
+// CDF estimates the fraction of samples less than or equal to x
+func (d *TDigest) CDF(x float64) float64 {
+	switch {
+	case d.count == 0:
+		return 0
+	case x < d.min:
+		return 0
+	case x >= d.max:
+		return 1
+	}
+
+	prevX, prevW := d.min, 0.0
+	cum := 0.0
+	for _, c := range d.merged() {
+		center := cum + c.weight/2
+		if x < c.mean {
+			return lerp(prevX, prevW, c.mean, center, x) / d.count
+		}
+		prevX, prevW = c.mean, center
+		cum += c.weight
+	}
+	return lerp(prevX, prevW, d.max, d.count, x) / d.count
+}
+
+// lerp returns the y value at x on the line through (x0,y0) and (x1,y1)
+func lerp(x0, y0, x1, y1, x float64) float64 {
+	if x1 == x0 {
+		return y1
+	}
+	return y0 + (y1-y0)*(x-x0)/(x1-x0)
+}
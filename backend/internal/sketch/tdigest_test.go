@@ -0,0 +1,52 @@
+package sketch
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// TestTDigestQuantiles checks quantiles of a uniform sample split across two
+// merged digests stay close to the exact values
+func TestTDigestQuantiles(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	a, b := NewTDigest(100), NewTDigest(100)
+	for i := 0; i < 100000; i++ {
+		x := rng.Float64() * 1000
+		if i%3 == 0 {
+			a.Add(x)
+		} else {
+			b.Add(x)
+		}
+	}
+	a.Merge(b)
+
+	if a.Count() != 100000 {
+		t.Errorf("Count() = %v; want 100000", a.Count())
+	}
+	for _, q := range []float64{0.5, 0.9, 0.99} {
+		if got, want := a.Quantile(q), q*1000; math.Abs(got-want) > 10 {
+			t.Errorf("Quantile(%v) = %.2f; want %.2f ± 10", q, got, want)
+		}
+	}
+	if got := a.CDF(250); math.Abs(got-0.25) > 0.01 {
+		t.Errorf("CDF(250) = %.4f; want 0.25 ± 0.01", got)
+	}
+}
+
+// TestTDigestSmall checks exact answers for tiny samples and empty digests
+func TestTDigestSmall(t *testing.T) {
+	d := NewTDigest(100)
+	if d.Quantile(0.5) != 0 || d.Mean() != 0 {
+		t.Errorf("empty digest should report zeros")
+	}
+	for _, x := range []float64{1, 2, 3, 4, 5} {
+		d.Add(x)
+	}
+	if got := d.Quantile(0.5); got != 3 {
+		t.Errorf("Quantile(0.5) = %v; want 3", got)
+	}
+	if d.Min() != 1 || d.Max() != 5 || d.Mean() != 3 {
+		t.Errorf("Min/Max/Mean = %v/%v/%v; want 1/5/3", d.Min(), d.Max(), d.Mean())
+	}
+}
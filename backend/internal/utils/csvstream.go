@@ -4,8 +4,6 @@ import (
 	"encoding/csv"
 	"io"
 	"os"
-	"strconv"
-	"time"
 
 	"github.com/GimhaniHM/backend/internal/models"
 )
@@ -38,32 +36,21 @@ func ReadTransactions(path string) ([]models.Transaction, error) {
 		if err != nil {
 			return nil, err
 		}
-		// Parse required fields from the record
-		td, _ := time.Parse("2006-01-02", rec[1])
-		price, _ := strconv.ParseFloat(rec[8], 64)
-		qty, _ := strconv.Atoi(rec[9])
-		tot := float64(qty) * price
-		stock, _ := strconv.Atoi(rec[11])
-		ad, _ := time.Parse("2006-01-02", rec[12])
-
 		// Create a Transaction struct from the record
-		out = append(out, models.Transaction{
-			TransactionID:   rec[0],
-			TransactionDate: td,
-			UserID:          rec[2],
-			Country:         rec[3],
-			Region:          rec[4],
-			ProductID:       rec[5],
-			ProductName:     rec[6],
-			Category:        rec[7],
-			Price:           price,
-			Quantity:        qty,
-			TotalPrice:      tot,
-			StockQuantity:   stock,
-			AddedDate:       ad,
-		})
+		out = append(out, ParseRecord(rec))
 	}
 
 	// Return the final list of transactions
 	return out, nil
 }
+
+// defaultLayout parses records in the default column order
+var defaultLayout = DefaultLayout()
+
+// ParseRecord converts a single CSV record in the default column order into
+// a Transaction. Malformed numeric or date fields are left at their zero
+// values.
+func ParseRecord(rec []string) models.Transaction {
+	t, _ := defaultLayout.Parse(rec)
+	return t
+}
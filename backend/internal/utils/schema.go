@@ -0,0 +1,197 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/GimhaniHM/backend/internal/models"
+)
+
+// SchemaFields are the transaction fields a CSV column can be mapped to, in
+// the order of the default column layout
+var SchemaFields = []string{
+	"transaction_id", "transaction_date", "user_id", "country", "region",
+	"product_id", "product_name", "category", "price", "quantity",
+	"total_price", "stock_quantity", "added_date",
+}
+
+// fields that may be missing from a mapped CSV (left at their zero value)
+var optionalFields = map[string]bool{
+	"transaction_id": true,
+	"total_price":    true, // recomputed as price * quantity
+	"stock_quantity": true,
+	"added_date":     true,
+}
+
+// DefaultDateFormat is the layout of transaction and added dates
+const DefaultDateFormat = "2006-01-02"
+
+// Schema describes the layout of a transactions CSV
+type Schema struct {
+	// Columns maps transaction fields to CSV header names. When empty the
+	// default column order is assumed and the header is not checked;
+	// otherwise every field is located by header name, and fields not
+	// listed are looked up under their own name.
+	Columns map[string]string
+
+	// DateFormat is the Go time layout of the date columns
+	DateFormat string
+
+	// Delimiter separates fields (',' when zero)
+	Delimiter rune
+}
+
+// positions of the fields in SchemaFields
+const (
+	fieldTransactionID = iota
+	fieldTransactionDate
+	fieldUserID
+	fieldCountry
+	fieldRegion
+	fieldProductID
+	fieldProductName
+	fieldCategory
+	fieldPrice
+	fieldQuantity
+	fieldTotalPrice
+	fieldStockQuantity
+	fieldAddedDate
+	numFields
+)
+
+// Layout resolves a Schema against a CSV header to parse records
+type Layout struct {
+	index      [numFields]int // column of each field, -1 when not in the CSV
+	dateFormat string
+}
+
+// DefaultLayout parses records in the default column order
+func DefaultLayout() *Layout {
+	l := &Layout{dateFormat: DefaultDateFormat}
+	for i := range l.index {
+		l.index[i] = i
+	}
+	return l
+}
+
+// Validate checks that the schema only maps known fields and has a usable
+// date format
+func (s Schema) Validate() error {
+	for field, col := range s.Columns {
+		if !isSchemaField(field) {
+			return fmt.Errorf("unknown field %q (want one of %s)", field, strings.Join(SchemaFields, ", "))
+		}
+		if strings.TrimSpace(col) == "" {
+			return fmt.Errorf("field %q is mapped to an empty column name", field)
+		}
+	}
+	if s.DateFormat != "" {
+		ref := time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC)
+		if got, err := time.Parse(s.DateFormat, ref.Format(s.DateFormat)); err != nil || !got.Equal(ref) {
+			return fmt.Errorf("date format %q is not a Go time layout for a full date (e.g. 2006-01-02)", s.DateFormat)
+		}
+	}
+	return nil
+}
+
+// isSchemaField reports whether name is one of SchemaFields
+func isSchemaField(name string) bool {
+	for _, f := range SchemaFields {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Layout resolves the schema against a CSV header. It fails when a required
+// field's column is missing from the header.
+func (s Schema) Layout(header []string) (*Layout, error) {
+	l := DefaultLayout()
+	if s.DateFormat != "" {
+		l.dateFormat = s.DateFormat
+	}
+	if len(s.Columns) == 0 {
+		return l, nil
+	}
+
+	byName := make(map[string]int, len(header))
+	for i, h := range header {
+		byName[strings.TrimSpace(strings.TrimPrefix(h, "\ufeff"))] = i
+	}
+	var missing []string
+	for fi, f := range SchemaFields {
+		col := f
+		if c, ok := s.Columns[f]; ok {
+			col = c
+		}
+		l.index[fi] = -1
+		if i, ok := byName[col]; ok {
+			l.index[fi] = i
+		} else if !optionalFields[f] {
+			missing = append(missing, fmt.Sprintf("%s (column %q)", f, col))
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("CSV header is missing %s", strings.Join(missing, ", "))
+	}
+	return l, nil
+}
+
+// field returns column fi of rec, or "" when it is not in the CSV
+func (l *Layout) field(rec []string, fi int) string {
+	if i := l.index[fi]; i >= 0 && i < len(rec) {
+		return rec[i]
+	}
+	return ""
+}
+
+// UserID returns the user ID of a record without parsing the rest
+func (l *Layout) UserID(rec []string) string {
+	return l.field(rec, fieldUserID)
+}
+
+// Parse converts a CSV record into a Transaction. It fails when the date,
+// price or quantity, or a non-empty stock quantity or added date, does not
+// parse; the fields that did parse are still filled in.
+func (l *Layout) Parse(rec []string) (models.Transaction, error) {
+	f := func(fi int) string { return l.field(rec, fi) }
+	var bad []string
+	check := func(fi int, err error) {
+		if err != nil && (f(fi) != "" || !optionalFields[SchemaFields[fi]]) {
+			bad = append(bad, fmt.Sprintf("%s %q", SchemaFields[fi], f(fi)))
+		}
+	}
+	td, err := time.Parse(l.dateFormat, f(fieldTransactionDate))
+	check(fieldTransactionDate, err)
+	price, err := strconv.ParseFloat(f(fieldPrice), 64)
+	check(fieldPrice, err)
+	qty, err := strconv.Atoi(f(fieldQuantity))
+	check(fieldQuantity, err)
+	stock, err := strconv.Atoi(f(fieldStockQuantity))
+	check(fieldStockQuantity, err)
+	ad, err := time.Parse(l.dateFormat, f(fieldAddedDate))
+	check(fieldAddedDate, err)
+
+	t := models.Transaction{
+		TransactionID:   f(fieldTransactionID),
+		TransactionDate: td,
+		UserID:          f(fieldUserID),
+		Country:         f(fieldCountry),
+		Region:          f(fieldRegion),
+		ProductID:       f(fieldProductID),
+		ProductName:     f(fieldProductName),
+		Category:        f(fieldCategory),
+		Price:           price,
+		Quantity:        qty,
+		TotalPrice:      float64(qty) * price,
+		StockQuantity:   stock,
+		AddedDate:       ad,
+	}
+	if len(bad) > 0 {
+		return t, fmt.Errorf("invalid %s", strings.Join(bad, ", "))
+	}
+	return t, nil
+}
@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSchemaLayoutMapsHeader(t *testing.T) {
+	s := Schema{
+		Columns:    map[string]string{"user_id": "customer", "transaction_date": "date", "price": "unit_price"},
+		DateFormat: "02/01/2006",
+	}
+	// Columns in any order; optional ones may be missing
+	header := []string{"\ufeffdate", "quantity", "unit_price", "customer", "country", "region", "product_id", "product_name", "category"}
+	l, err := s.Layout(header)
+	if err != nil {
+		t.Fatalf("Layout error: %v", err)
+	}
+
+	rec := []string{"31/12/2024", "3", "2.5", "U9", "USA", "West", "P1", "Prod1", "Toys"}
+	if got := l.UserID(rec); got != "U9" {
+		t.Errorf("UserID = %q; want U9", got)
+	}
+	tx, err := l.Parse(rec)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	if want := time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC); !tx.TransactionDate.Equal(want) {
+		t.Errorf("TransactionDate = %v; want %v", tx.TransactionDate, want)
+	}
+	if tx.TotalPrice != 7.5 || tx.ProductName != "Prod1" || tx.TransactionID != "" || tx.StockQuantity != 0 {
+		t.Errorf("unexpected transaction %+v", tx)
+	}
+}
+
+func TestSchemaLayoutMissingColumn(t *testing.T) {
+	s := Schema{Columns: map[string]string{"user_id": "customer"}}
+	_, err := s.Layout(strings.Split(headerWithoutUserID, ","))
+	if err == nil || !strings.Contains(err.Error(), `user_id (column "customer")`) {
+		t.Fatalf("err = %v; want the missing user_id column", err)
+	}
+}
+
+func TestSchemaValidate(t *testing.T) {
+	for _, s := range []Schema{
+		{Columns: map[string]string{"customer": "user"}},
+		{Columns: map[string]string{"user_id": " "}},
+		{DateFormat: "2006-01"},
+	} {
+		if err := s.Validate(); err == nil {
+			t.Errorf("Validate(%+v) = nil; want an error", s)
+		}
+	}
+	if err := (Schema{DateFormat: "02.01.2006"}).Validate(); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+}
+
+// headerWithoutUserID is the default header with user_id renamed to user
+const headerWithoutUserID = "transaction_id,transaction_date,user,country,region,product_id,product_name,category,price,quantity,total_price,stock_quantity,added_date"
+
+// TestLayoutParseReportsBadFields checks Parse names each bad field and keeps the valid ones
+func TestLayoutParseReportsBadFields(t *testing.T) {
+	rec := strings.Split("T1,31/12/2024,U1,USA,West,P1,Prod1,Toys,1.5,x,,,", ",")
+	tx, err := DefaultLayout().Parse(rec)
+	if err == nil || err.Error() != `invalid transaction_date "31/12/2024", quantity "x"` {
+		t.Errorf("err = %v; want the bad date and quantity", err)
+	}
+	if tx.Price != 1.5 || tx.UserID != "U1" {
+		t.Errorf("Parse = %+v; want the valid fields filled in", tx)
+	}
+}
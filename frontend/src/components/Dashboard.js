@@ -34,7 +34,7 @@ export default function Dashboard() {
   useEffect(() => {
     fetch('/api/products/top?limit=20')
       .then(res => res.json())
-      .then(data => setTopProducts(data));
+      .then(data => setTopProducts(data.data || []));
 
     fetch('/api/sales/monthly')
       .then(res => res.json())
@@ -42,7 +42,7 @@ export default function Dashboard() {
 
     fetch('/api/regions/top?limit=30')
       .then(res => res.json())
-      .then(data => setTopRegions(data));
+      .then(data => setTopRegions(data.data || []));
   }, []);
 
   const totalPages = Math.ceil(totalCount / pageSize);